@@ -2,7 +2,10 @@ package db
 
 import (
 	"context"
+	"database/sql"
+
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 type Database struct {
@@ -17,3 +20,9 @@ func InitDB(connStr string) (*Database, error) {
 
 	return &Database{Pool: pool}, nil
 }
+
+// SQL returns a database/sql handle backed by the pool. Closing it leaves the
+// pool open.
+func (d *Database) SQL() *sql.DB {
+	return stdlib.OpenDBFromPool(d.Pool)
+}
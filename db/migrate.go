@@ -0,0 +1,314 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// migrationsDir maps each dialect to its directory inside migrationsFS.
+var migrationsDir = map[Dialect]string{
+	DialectPostgres: "migrations",
+	DialectSQLite:   "migrations/sqlite",
+}
+
+const (
+	upMarker   = "-- +migrate Up"
+	downMarker = "-- +migrate Down"
+
+	// migrationLockID is the PostgreSQL advisory lock taken while migrating,
+	// so that several instances starting at once do not race each other.
+	migrationLockID = 7_401_240_001
+)
+
+var ErrSchemaAhead = errors.New("database schema is newer than this binary")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// NewMigrator loads the migrations embedded for dialect. Migration files are
+// named <version>_<name>.sql and split into sections by "-- +migrate Up" and
+// "-- +migrate Down" comments; a file without markers is all Up.
+func NewMigrator(conn *sql.DB, dialect Dialect) (*Migrator, error) {
+	dir, ok := migrationsDir[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unknown migration dialect %q", dialect)
+	}
+	entries, err := fs.ReadDir(migrationsFS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		migration, err := parseMigration(dir, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[migration.Version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), migration.Version)
+		}
+		seen[migration.Version] = entry.Name()
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return &Migrator{db: conn, dialect: dialect, migrations: migrations}, nil
+}
+
+func parseMigration(dir, name string) (Migration, error) {
+	prefix, rest, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+	if !ok {
+		return Migration{}, fmt.Errorf("migration %s: name must be <version>_<name>.sql", name)
+	}
+	version, err := strconv.Atoi(prefix)
+	if err != nil || version <= 0 {
+		return Migration{}, fmt.Errorf("migration %s: invalid version %q", name, prefix)
+	}
+	body, err := migrationsFS.ReadFile(path.Join(dir, name))
+	if err != nil {
+		return Migration{}, err
+	}
+
+	up, down := string(body), ""
+	if i := strings.Index(up, downMarker); i >= 0 {
+		up, down = up[:i], up[i+len(downMarker):]
+	}
+	up = strings.Replace(up, upMarker, "", 1)
+
+	return Migration{
+		Version: version,
+		Name:    rest,
+		Up:      strings.TrimSpace(up),
+		Down:    strings.TrimSpace(down),
+	}, nil
+}
+
+// Latest returns the highest version known to this binary.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkAhead(versions); err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			insert := m.bind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`)
+			err := m.inTx(ctx, conn, migration.Up, insert, migration.Version, migration.Name, time.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("migration %03d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the most recently applied migration. It returns nil when
+// nothing is applied.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkAhead(versions); err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %03d_%s has no down section", migration.Version, migration.Name)
+			}
+			remove := m.bind(`DELETE FROM schema_migrations WHERE version = ?`)
+			if err := m.inTx(ctx, conn, migration.Down, remove, migration.Version); err != nil {
+				return fmt.Errorf("migration %03d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = &migration
+			return nil
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status reports every known migration along with when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Migration: migration}
+			if appliedAt, ok := versions[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return m.checkAhead(versions)
+	})
+	return statuses, err
+}
+
+// Pending returns the number of migrations not yet applied. It fails with
+// ErrSchemaAhead when the database has versions this binary does not know.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) checkAhead(versions map[int]time.Time) error {
+	for version := range versions {
+		if version > m.Latest() {
+			return fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrSchemaAhead, version, m.Latest())
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	create := `CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    version    INTEGER PRIMARY KEY,
+    name       TEXT      NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`
+	if _, err := conn.ExecContext(ctx, create); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// inTx runs script and then the bookkeeping statement in one transaction.
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect == DialectPostgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}
+	return fn(conn)
+}
+
+// bind rewrites ? placeholders into the dialect's parameter syntax.
+func (m *Migrator) bind(query string) string {
+	if m.dialect != DialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
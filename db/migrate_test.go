@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestMigrator(t *testing.T) (*Migrator, *SQLite) {
+	t.Helper()
+	sqlite, err := InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { sqlite.DB.Close() })
+	migrator, err := NewMigrator(sqlite.DB, DialectSQLite)
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	return migrator, sqlite
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	ctx := context.Background()
+	migrator, _ := newTestMigrator(t)
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != migrator.Latest() {
+		t.Fatalf("Up applied %d migrations, want %d", len(applied), migrator.Latest())
+	}
+	if pending, err := migrator.Pending(ctx); err != nil || pending != 0 {
+		t.Fatalf("Pending after Up = %d, %v; want 0", pending, err)
+	}
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("second Up applied %d migrations, %v; want none", len(applied), err)
+	}
+
+	for version := migrator.Latest(); version > 0; version-- {
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			t.Fatalf("Down from version %d failed: %v", version, err)
+		}
+		if reverted == nil || reverted.Version != version {
+			t.Fatalf("Down reverted %+v, want version %d", reverted, version)
+		}
+	}
+	if reverted, err := migrator.Down(ctx); err != nil || reverted != nil {
+		t.Fatalf("Down with nothing applied = %+v, %v; want nil", reverted, err)
+	}
+	if pending, err := migrator.Pending(ctx); err != nil || pending != migrator.Latest() {
+		t.Fatalf("Pending after Down = %d, %v; want %d", pending, err, migrator.Latest())
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up after Down failed: %v", err)
+	}
+}
+
+func TestMigrateSchemaAhead(t *testing.T) {
+	ctx := context.Background()
+	migrator, sqlite := newTestMigrator(t)
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	_, err := sqlite.DB.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		migrator.Latest()+1, "from_the_future", time.Now().UTC())
+	if err != nil {
+		t.Fatalf("inserting a newer version failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"up", func() error { _, err := migrator.Up(ctx); return err }},
+		{"down", func() error { _, err := migrator.Down(ctx); return err }},
+		{"pending", func() error { _, err := migrator.Pending(ctx); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, ErrSchemaAhead) {
+				t.Errorf("got %v, want ErrSchemaAhead", err)
+			}
+		})
+	}
+}
+
+func TestParseMigrationNames(t *testing.T) {
+	for _, name := range []string{"init.sql", "abc_init.sql", "0_init.sql", "-1_init.sql"} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseMigration("migrations", name); err == nil {
+				t.Errorf("parseMigration(%q) succeeded, want an error", name)
+			}
+		})
+	}
+}
+
+func TestMigrationDialectsMatch(t *testing.T) {
+	postgres, err := NewMigrator(nil, DialectPostgres)
+	if err != nil {
+		t.Fatalf("NewMigrator(postgres) failed: %v", err)
+	}
+	sqlite, err := NewMigrator(nil, DialectSQLite)
+	if err != nil {
+		t.Fatalf("NewMigrator(sqlite) failed: %v", err)
+	}
+	if len(postgres.migrations) != len(sqlite.migrations) {
+		t.Fatalf("postgres has %d migrations, sqlite %d", len(postgres.migrations), len(sqlite.migrations))
+	}
+	for i, pg := range postgres.migrations {
+		lite := sqlite.migrations[i]
+		if pg.Version != lite.Version || pg.Name != lite.Name {
+			t.Errorf("migration %d is %03d_%s for postgres but %03d_%s for sqlite",
+				i, pg.Version, pg.Name, lite.Version, lite.Name)
+		}
+		for _, m := range []Migration{pg, lite} {
+			if m.Up == "" || m.Down == "" {
+				t.Errorf("migration %03d_%s lacks an up or down section", m.Version, m.Name)
+			}
+		}
+	}
+}
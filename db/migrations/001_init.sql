@@ -1,3 +1,4 @@
+-- +migrate Up
 CREATE TABLE IF NOT EXISTS users
 (
     id       serial PRIMARY KEY,
@@ -15,4 +16,6 @@ CREATE TABLE IF NOT EXISTS tasks
     is_overdue BOOLEAN DEFAULT FALSE
 );
 
-
+-- +migrate Down
+DROP TABLE IF EXISTS tasks;
+DROP TABLE IF EXISTS users;
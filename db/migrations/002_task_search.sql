@@ -0,0 +1,10 @@
+-- +migrate Up
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS search tsvector
+        GENERATED ALWAYS AS (to_tsvector('simple', title)) STORED;
+
+CREATE INDEX IF NOT EXISTS tasks_search_idx ON tasks USING GIN (search);
+
+-- +migrate Down
+DROP INDEX IF EXISTS tasks_search_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS search;
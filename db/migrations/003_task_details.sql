@@ -0,0 +1,29 @@
+-- +migrate Up
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS description  TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS priority     VARCHAR(16) NOT NULL DEFAULT 'normal'
+        CHECK (priority IN ('low', 'normal', 'high', 'urgent')),
+    ADD COLUMN IF NOT EXISTS created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+
+-- Rebuild the search vector so that descriptions are searchable too.
+ALTER TABLE tasks DROP COLUMN IF EXISTS search;
+ALTER TABLE tasks
+    ADD COLUMN search tsvector
+        GENERATED ALWAYS AS (to_tsvector('simple', title || ' ' || description)) STORED;
+CREATE INDEX IF NOT EXISTS tasks_search_idx ON tasks USING GIN (search);
+
+-- +migrate Down
+ALTER TABLE tasks DROP COLUMN IF EXISTS search;
+ALTER TABLE tasks
+    ADD COLUMN search tsvector
+        GENERATED ALWAYS AS (to_tsvector('simple', title)) STORED;
+CREATE INDEX IF NOT EXISTS tasks_search_idx ON tasks USING GIN (search);
+
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS completed_at,
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS priority,
+    DROP COLUMN IF EXISTS description;
@@ -0,0 +1,21 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS tags
+(
+    id      serial PRIMARY KEY,
+    user_id int references users (id) on delete cascade not null,
+    name    VARCHAR(64)                                 NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS task_tags
+(
+    task_id int references tasks (id) on delete cascade not null,
+    tag_id  int references tags (id) on delete cascade  not null,
+    PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_tags_tag_id_idx ON task_tags (tag_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS task_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,20 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS projects
+(
+    id         serial PRIMARY KEY,
+    user_id    int references users (id) on delete cascade not null,
+    name       VARCHAR(128)                                NOT NULL,
+    created_at TIMESTAMPTZ                                 NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ                                 NOT NULL DEFAULT now(),
+    UNIQUE (user_id, name)
+);
+
+-- Tasks without a project are in the user's inbox.
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS project_id int references projects (id) on delete set null;
+CREATE INDEX IF NOT EXISTS tasks_project_id_idx ON tasks (project_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS tasks_project_id_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS project_id;
+DROP TABLE IF EXISTS projects;
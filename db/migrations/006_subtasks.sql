@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS parent_id int references tasks (id) on delete cascade;
+CREATE INDEX IF NOT EXISTS tasks_parent_id_idx ON tasks (parent_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS tasks_parent_id_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,6 @@
+-- +migrate Up
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS recurrence TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE tasks DROP COLUMN IF EXISTS recurrence;
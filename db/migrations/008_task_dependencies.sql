@@ -0,0 +1,13 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS task_dependencies
+(
+    task_id       int references tasks (id) on delete cascade not null,
+    blocked_by_id int references tasks (id) on delete cascade not null,
+    PRIMARY KEY (task_id, blocked_by_id),
+    CHECK (task_id <> blocked_by_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_dependencies_blocked_by_id_idx ON task_dependencies (blocked_by_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS task_dependencies;
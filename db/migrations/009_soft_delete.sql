@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS tasks_deleted_at_idx ON tasks (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +migrate Down
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS tasks_deleted_at_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS deleted_at;
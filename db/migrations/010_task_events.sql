@@ -0,0 +1,15 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS task_events
+(
+    id         serial primary key,
+    task_id    int references tasks (id) on delete cascade not null,
+    actor_id   int references users (id) on delete set null,
+    action     varchar(20)                                 not null,
+    changes    jsonb                                       not null default '{}',
+    created_at timestamptz                                 not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS task_events_task_id_idx ON task_events (task_id, id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS task_events;
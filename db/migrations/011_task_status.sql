@@ -0,0 +1,21 @@
+-- +migrate Up
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS status            VARCHAR(16) NOT NULL DEFAULT 'todo'
+        CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled')),
+    ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ NOT NULL DEFAULT now();
+UPDATE tasks SET status            = CASE WHEN completed THEN 'done' ELSE 'todo' END,
+                 status_changed_at = COALESCE(completed_at, created_at);
+ALTER TABLE tasks DROP COLUMN IF EXISTS completed;
+
+UPDATE task_events SET action = 'status_changed', changes = '{"status": {"old": "todo", "new": "done"}}'
+WHERE action = 'completed';
+
+-- +migrate Down
+UPDATE task_events SET action = 'completed', changes = '{"completed": {"old": false, "new": true}}'
+WHERE action = 'status_changed' AND changes -> 'status' ->> 'new' = 'done';
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS completed BOOLEAN DEFAULT FALSE;
+UPDATE tasks SET completed = status = 'done';
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS status_changed_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS refresh_tokens
+(
+    id         serial primary key,
+    session_id varchar(64)                                 not null,
+    user_id    int references users (id) on delete cascade not null,
+    token_hash varchar(64) unique                          not null,
+    expires_at timestamptz                                 not null,
+    created_at timestamptz                                 not null default now(),
+    used_at    timestamptz,
+    revoked_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_session_id_idx ON refresh_tokens (session_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,12 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS login_failures
+(
+    login_key       varchar(320) primary key,
+    failures        int         not null,
+    last_failure_at timestamptz not null
+);
+
+CREATE INDEX IF NOT EXISTS login_failures_last_failure_at_idx ON login_failures (last_failure_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS login_failures;
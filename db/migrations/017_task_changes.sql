@@ -0,0 +1,82 @@
+-- +migrate Up
+-- The changes to the tasks of a user are numbered for sync tokens. Each write
+-- takes the next number from the counter on the user's row, which stays
+-- locked until the write commits, so the numbers follow the commit order.
+-- sync_horizon is the last number of the tombstones dropped so far.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS change_seq   BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS sync_horizon BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS change_seq BIGINT NOT NULL DEFAULT 0;
+
+UPDATE tasks t
+SET change_seq = n.seq
+FROM (SELECT id, row_number() OVER (PARTITION BY user_id ORDER BY updated_at, id) AS seq FROM tasks) n
+WHERE t.id = n.id;
+
+UPDATE users u
+SET change_seq = t.seq
+FROM (SELECT user_id, max(change_seq) AS seq FROM tasks GROUP BY user_id) t
+WHERE u.id = t.user_id;
+
+CREATE INDEX IF NOT EXISTS tasks_change_seq_idx ON tasks (user_id, change_seq);
+
+-- task_tombstones reports purged tasks to syncing clients.
+CREATE TABLE IF NOT EXISTS task_tombstones
+(
+    task_id    int primary key,
+    user_id    int references users (id) on delete cascade not null,
+    change_seq bigint                                      not null,
+    deleted_at timestamptz                                 not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS task_tombstones_change_seq_idx ON task_tombstones (user_id, change_seq);
+CREATE INDEX IF NOT EXISTS task_tombstones_deleted_at_idx ON task_tombstones (deleted_at);
+
+CREATE OR REPLACE FUNCTION next_task_change(owner int) RETURNS bigint AS $$
+    UPDATE users SET change_seq = change_seq + 1 WHERE id = owner RETURNING change_seq;
+$$ LANGUAGE sql;
+
+CREATE OR REPLACE FUNCTION number_task_change() RETURNS trigger AS $$
+BEGIN
+    NEW.change_seq := next_task_change(NEW.user_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Tasks deleted along with their user leave no tombstone.
+CREATE OR REPLACE FUNCTION bury_task() RETURNS trigger AS $$
+DECLARE
+    seq bigint := next_task_change(OLD.user_id);
+BEGIN
+    IF seq IS NOT NULL THEN
+        INSERT INTO task_tombstones (task_id, user_id, change_seq) VALUES (OLD.id, OLD.user_id, seq);
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Every change that clients see bumps the version.
+DROP TRIGGER IF EXISTS tasks_number_change ON tasks;
+CREATE TRIGGER tasks_number_change
+    BEFORE INSERT OR UPDATE OF version ON tasks
+    FOR EACH ROW EXECUTE FUNCTION number_task_change();
+
+DROP TRIGGER IF EXISTS tasks_bury ON tasks;
+CREATE TRIGGER tasks_bury
+    AFTER DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION bury_task();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS tasks_bury ON tasks;
+DROP TRIGGER IF EXISTS tasks_number_change ON tasks;
+DROP FUNCTION IF EXISTS bury_task();
+DROP FUNCTION IF EXISTS number_task_change();
+DROP FUNCTION IF EXISTS next_task_change(int);
+DROP TABLE IF EXISTS task_tombstones;
+DROP INDEX IF EXISTS tasks_change_seq_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS change_seq;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS sync_horizon,
+    DROP COLUMN IF EXISTS change_seq;
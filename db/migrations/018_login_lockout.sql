@@ -0,0 +1,6 @@
+-- +migrate Up
+ALTER TABLE login_failures
+    ADD COLUMN IF NOT EXISTS locked_until timestamptz;
+
+-- +migrate Down
+ALTER TABLE login_failures DROP COLUMN IF EXISTS locked_until;
@@ -0,0 +1,21 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS users
+(
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT UNIQUE NOT NULL,
+    password TEXT        NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tasks
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    title      TEXT                                            NOT NULL,
+    completed  BOOLEAN DEFAULT FALSE,
+    deadline   TIMESTAMP,
+    is_overdue BOOLEAN DEFAULT FALSE
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS tasks;
+DROP TABLE IF EXISTS users;
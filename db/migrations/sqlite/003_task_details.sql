@@ -0,0 +1,18 @@
+-- +migrate Up
+ALTER TABLE tasks ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN priority TEXT NOT NULL DEFAULT 'normal'
+    CHECK (priority IN ('low', 'normal', 'high', 'urgent'));
+-- SQLite cannot add a column with a non-constant default, so timestamps are
+-- backfilled after the columns exist.
+ALTER TABLE tasks ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE tasks ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMP;
+UPDATE tasks SET created_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now'),
+                 updated_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now');
+
+-- +migrate Down
+ALTER TABLE tasks DROP COLUMN completed_at;
+ALTER TABLE tasks DROP COLUMN updated_at;
+ALTER TABLE tasks DROP COLUMN created_at;
+ALTER TABLE tasks DROP COLUMN priority;
+ALTER TABLE tasks DROP COLUMN description;
@@ -0,0 +1,21 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS tags
+(
+    id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    name    TEXT                                            NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS task_tags
+(
+    task_id INTEGER REFERENCES tasks (id) ON DELETE CASCADE NOT NULL,
+    tag_id  INTEGER REFERENCES tags (id) ON DELETE CASCADE  NOT NULL,
+    PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_tags_tag_id_idx ON task_tags (tag_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS task_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS projects
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    name       TEXT                                            NOT NULL,
+    created_at TIMESTAMP                                       NOT NULL,
+    updated_at TIMESTAMP                                       NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+-- Tasks without a project are in the user's inbox.
+ALTER TABLE tasks ADD COLUMN project_id INTEGER REFERENCES projects (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS tasks_project_id_idx ON tasks (project_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS tasks_project_id_idx;
+ALTER TABLE tasks DROP COLUMN project_id;
+DROP TABLE IF EXISTS projects;
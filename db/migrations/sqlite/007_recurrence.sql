@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE tasks ADD COLUMN recurrence TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE tasks DROP COLUMN recurrence;
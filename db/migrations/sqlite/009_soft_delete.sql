@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE tasks ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS tasks_deleted_at_idx ON tasks (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +migrate Down
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS tasks_deleted_at_idx;
+ALTER TABLE tasks DROP COLUMN deleted_at;
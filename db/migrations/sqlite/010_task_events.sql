@@ -0,0 +1,15 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS task_events
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id    INTEGER REFERENCES tasks (id) ON DELETE CASCADE NOT NULL,
+    actor_id   INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    action     TEXT                                            NOT NULL,
+    changes    TEXT                                            NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP                                       NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_events_task_id_idx ON task_events (task_id, id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS task_events;
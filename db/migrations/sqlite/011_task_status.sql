@@ -0,0 +1,19 @@
+-- +migrate Up
+ALTER TABLE tasks ADD COLUMN status TEXT NOT NULL DEFAULT 'todo'
+    CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled'));
+ALTER TABLE tasks ADD COLUMN status_changed_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+UPDATE tasks SET status            = CASE WHEN completed THEN 'done' ELSE 'todo' END,
+                 status_changed_at = COALESCE(completed_at, created_at);
+ALTER TABLE tasks DROP COLUMN completed;
+
+UPDATE task_events SET action = 'status_changed', changes = '{"status":{"old":"todo","new":"done"}}'
+WHERE action = 'completed';
+
+-- +migrate Down
+UPDATE task_events SET action = 'completed', changes = '{"completed":{"old":false,"new":true}}'
+WHERE action = 'status_changed' AND json_extract(changes, '$.status.new') = 'done';
+
+ALTER TABLE tasks ADD COLUMN completed BOOLEAN DEFAULT FALSE;
+UPDATE tasks SET completed = status = 'done';
+ALTER TABLE tasks DROP COLUMN status_changed_at;
+ALTER TABLE tasks DROP COLUMN status;
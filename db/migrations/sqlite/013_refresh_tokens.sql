@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS refresh_tokens
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT                                            NOT NULL,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    token_hash TEXT UNIQUE                                     NOT NULL,
+    expires_at TIMESTAMP                                       NOT NULL,
+    created_at TIMESTAMP                                       NOT NULL,
+    used_at    TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_session_id_idx ON refresh_tokens (session_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS refresh_tokens;
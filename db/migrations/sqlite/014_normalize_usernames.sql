@@ -0,0 +1,14 @@
+-- +migrate Up
+-- Usernames are now stored trimmed and lowercased. Names that would collide
+-- once normalized are left as they are.
+UPDATE users
+SET username = lower(trim(username))
+WHERE username <> lower(trim(username))
+  AND NOT EXISTS (SELECT 1
+                  FROM users other
+                  WHERE other.id <> users.id
+                    AND lower(trim(other.username)) = lower(trim(users.username)));
+
+-- +migrate Down
+-- The original spelling of normalized usernames is not kept.
+SELECT 1;
@@ -0,0 +1,12 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS login_failures
+(
+    login_key       TEXT PRIMARY KEY,
+    failures        INTEGER   NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS login_failures_last_failure_at_idx ON login_failures (last_failure_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS login_failures;
@@ -0,0 +1,7 @@
+-- +migrate Up
+-- next_occurrence_id is not a foreign key: it keeps marking the occurrence as
+-- created after that task is purged, so that none is created again.
+ALTER TABLE tasks ADD COLUMN next_occurrence_id INTEGER;
+
+-- +migrate Down
+ALTER TABLE tasks DROP COLUMN next_occurrence_id;
@@ -0,0 +1,65 @@
+-- +migrate Up
+-- The changes to the tasks of a user are numbered for sync tokens, from a
+-- counter on the user's row. sync_horizon is the last number of the
+-- tombstones dropped so far.
+ALTER TABLE users ADD COLUMN change_seq INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN sync_horizon INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN change_seq INTEGER NOT NULL DEFAULT 0;
+
+UPDATE tasks
+SET change_seq = n.seq
+FROM (SELECT id, row_number() OVER (PARTITION BY user_id ORDER BY updated_at, id) AS seq FROM tasks) AS n
+WHERE tasks.id = n.id;
+
+UPDATE users
+SET change_seq = (SELECT coalesce(max(change_seq), 0) FROM tasks WHERE tasks.user_id = users.id);
+
+CREATE INDEX IF NOT EXISTS tasks_change_seq_idx ON tasks (user_id, change_seq);
+
+-- task_tombstones reports purged tasks to syncing clients.
+CREATE TABLE IF NOT EXISTS task_tombstones
+(
+    task_id    INTEGER PRIMARY KEY,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    change_seq INTEGER                                         NOT NULL,
+    deleted_at TIMESTAMP                                       NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_tombstones_change_seq_idx ON task_tombstones (user_id, change_seq);
+CREATE INDEX IF NOT EXISTS task_tombstones_deleted_at_idx ON task_tombstones (deleted_at);
+
+CREATE TRIGGER IF NOT EXISTS tasks_number_insert
+    AFTER INSERT ON tasks
+BEGIN
+    UPDATE users SET change_seq = change_seq + 1 WHERE id = NEW.user_id;
+    UPDATE tasks SET change_seq = (SELECT change_seq FROM users WHERE id = NEW.user_id) WHERE id = NEW.id;
+END;
+
+-- Every change that clients see bumps the version.
+CREATE TRIGGER IF NOT EXISTS tasks_number_update
+    AFTER UPDATE OF version ON tasks
+BEGIN
+    UPDATE users SET change_seq = change_seq + 1 WHERE id = NEW.user_id;
+    UPDATE tasks SET change_seq = (SELECT change_seq FROM users WHERE id = NEW.user_id) WHERE id = NEW.id;
+END;
+
+-- Tasks deleted along with their user leave no tombstone.
+CREATE TRIGGER IF NOT EXISTS tasks_bury
+    AFTER DELETE ON tasks
+BEGIN
+    UPDATE users SET change_seq = change_seq + 1 WHERE id = OLD.user_id;
+    INSERT INTO task_tombstones (task_id, user_id, change_seq, deleted_at)
+    SELECT OLD.id, OLD.user_id, change_seq, strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+    FROM users
+    WHERE id = OLD.user_id;
+END;
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS tasks_bury;
+DROP TRIGGER IF EXISTS tasks_number_update;
+DROP TRIGGER IF EXISTS tasks_number_insert;
+DROP TABLE IF EXISTS task_tombstones;
+DROP INDEX IF EXISTS tasks_change_seq_idx;
+ALTER TABLE tasks DROP COLUMN change_seq;
+ALTER TABLE users DROP COLUMN sync_horizon;
+ALTER TABLE users DROP COLUMN change_seq;
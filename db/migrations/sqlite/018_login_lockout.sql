@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE login_failures ADD COLUMN locked_until TIMESTAMP;
+
+-- +migrate Down
+ALTER TABLE login_failures DROP COLUMN locked_until;
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+type SQLite struct {
+	DB *sql.DB
+}
+
+// InitSQLite opens the SQLite database file at path, creating it when missing.
+// The driver is pure Go, so builds without cgo work too. Times are written in
+// the format SQLite's date functions read.
+func InitSQLite(path string) (*SQLite, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite", path)
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; sharing one connection avoids SQLITE_BUSY.
+	conn.SetMaxOpenConns(1)
+	return &SQLite{DB: conn}, nil
+}
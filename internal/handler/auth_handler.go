@@ -1,36 +1,45 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"tasklist/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"tasklist/internal/service"
 )
 
 type AuthHandler struct {
 	svc service.Auth
+	log *logrus.Logger
 }
 
-func NewAuthHandler(svc service.Auth) *AuthHandler { return &AuthHandler{svc: svc} }
+func NewAuthHandler(svc service.Auth, log *logrus.Logger) *AuthHandler {
+	return &AuthHandler{svc: svc, log: log}
+}
 
-func (h *AuthHandler) Register(api *gin.RouterGroup) {
+func (h *AuthHandler) Register(api *gin.RouterGroup, requireAuth gin.HandlerFunc) {
 	authGroup := api.Group("/auth")
 	{
 		authGroup.POST("/register", h.RegisterUser)
 		authGroup.POST("/login", h.Login)
+		authGroup.POST("/refresh", h.Refresh)
+		authGroup.POST("/logout", requireAuth, h.Logout)
 	}
 }
 
 // RegisterUser godoc
 // @Summary      Register a new user
-// @Description  Creates a new user account with username and password
+// @Description  Creates a new user account with username and password, and logs it in. Usernames are matched case-insensitively, and passwords must satisfy the password policy
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        input  body  models.AuthRequest  true  "User credentials"
-// @Success      200  {object}  map[string]string
+// @Success      201  {object}  models.AuthResponse
 // @Failure      400  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
 // @Router       /auth/register [post]
 func (h *AuthHandler) RegisterUser(c *gin.Context) {
 	var req models.AuthRequest
@@ -39,18 +48,18 @@ func (h *AuthHandler) RegisterUser(c *gin.Context) {
 		return
 	}
 
-	token, err := h.svc.Register(c, req.Username, req.Password)
+	resp, err := h.svc.Register(c, req.Username, req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		errorResponse(c, h.log, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.AuthResponse{Token: token})
+	c.JSON(http.StatusCreated, resp)
 }
 
 // Login godoc
 // @Summary      Login user
-// @Description  Authenticate user and return JWT token
+// @Description  Authenticate user and return an access token with the refresh token that renews it
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -58,6 +67,8 @@ func (h *AuthHandler) RegisterUser(c *gin.Context) {
 // @Success      200  {object}  models.AuthResponse
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Header       429  {integer}  Retry-After  "Seconds until logins are accepted again"
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.AuthRequest
@@ -65,10 +76,56 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	token, err := h.svc.Login(c, req.Username, req.Password)
+	resp, err := h.svc.Login(c, req.Username, req.Password, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(throttled.RetryAfterSeconds()))
+		}
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Refresh godoc
+// @Summary      Refresh access token
+// @Description  Exchange a refresh token for a new access token and a new refresh token, which replaces it. Each refresh token works once; presenting a used one again revokes its whole session
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.RefreshRequest  true  "Refresh token"
+// @Success      200  {object}  models.AuthResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.svc.Refresh(c, req.RefreshToken)
+	if err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Logout godoc
+// @Summary      Logout
+// @Description  Revoke the session of the access token, along with its refresh tokens
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.svc.Logout(c, c.GetString(models.SessionCtxKey)); err != nil {
+		errorResponse(c, h.log, err)
 		return
 	}
-	c.JSON(http.StatusOK, models.AuthResponse{Token: token})
+	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
 }
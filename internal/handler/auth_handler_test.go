@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// login posts credentials from a client address.
+func (s *testServer) login(username, password, clientIP string) *httptest.ResponseRecorder {
+	body := `{"username":"` + username + `","password":"` + password + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = clientIP + ":40000"
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginThrottled(t *testing.T) {
+	s := newTestServer(t)
+	// Two failures are allowed; the third locks the username out for the
+	// 30-second backoff of testConfig.
+	tests := []struct {
+		name       string
+		password   string
+		clientIP   string
+		want       int
+		retryAfter string
+	}{
+		{"first failure", "wrong", "192.0.2.1", http.StatusUnauthorized, ""},
+		{"second failure", "wrong", "192.0.2.1", http.StatusUnauthorized, ""},
+		{"failure past the allowance", "wrong", "192.0.2.2", http.StatusUnauthorized, ""},
+		{"locked out", "wrong", "192.0.2.1", http.StatusTooManyRequests, "30"},
+		{"locked out with the right password", testPassword, "192.0.2.3", http.StatusTooManyRequests, "30"},
+	}
+	for _, tt := range tests {
+		w := s.login("alice", tt.password, tt.clientIP)
+		if w.Code != tt.want {
+			t.Fatalf("%s: login = %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+			t.Errorf("%s: Retry-After = %q, want %q", tt.name, got, tt.retryAfter)
+		}
+	}
+
+	if w := s.login("bob", "wrong", "192.0.2.1"); w.Code != http.StatusUnauthorized {
+		t.Errorf("login as another user = %d, want 401", w.Code)
+	}
+}
+
+func TestLoginSucceeds(t *testing.T) {
+	s := newTestServer(t)
+	for i := 0; i < 2; i++ {
+		s.login("alice", "wrong", "192.0.2.1")
+	}
+	// Usernames are matched case-insensitively, and a success forgets the
+	// failures before it.
+	if w := s.login(" Alice ", testPassword, "192.0.2.1"); w.Code != http.StatusOK {
+		t.Fatalf("login = %d %s, want 200", w.Code, w.Body)
+	}
+	for i := 0; i < 3; i++ {
+		if w := s.login("alice", "wrong", "192.0.2.1"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d after a success = %d, want 401", i+1, w.Code)
+		}
+	}
+}
@@ -1,25 +1,41 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	_ "tasklist/docs"
 	"tasklist/internal/service"
+	"tasklist/pkg/auth"
+	"tasklist/pkg/middleware"
 )
 
 type Handler struct {
-	AuthService service.Auth
-	TaskService service.Task
-	log         *logrus.Logger
+	AuthService    service.Auth
+	TaskService    service.Task
+	ProjectService service.Project
+	TagService     service.Tag
+	keys           *auth.Keyset
+	log            *logrus.Logger
 }
 
-func NewHandler(service *service.Service, log *logrus.Logger) *Handler {
+func NewHandler(service *service.Service, keys *auth.Keyset, log *logrus.Logger) *Handler {
 	return &Handler{
-		AuthService: service.AuthService,
-		TaskService: service.TaskService,
-		log:         log,
+		keys:           keys,
+		AuthService:    service.AuthService,
+		TaskService:    service.TaskService,
+		ProjectService: service.ProjectService,
+		TagService:     service.TagService,
+		log:            log,
 	}
 }
 
@@ -27,15 +43,112 @@ func (h *Handler) Init() *gin.Engine {
 	router := gin.New()
 	router.Use(gin.Recovery(), gin.Logger())
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/.well-known/jwks.json", h.jwks)
 
-	authHandler := NewAuthHandler(h.AuthService)
+	requireAuth := middleware.JWTAuth(h.keys, h.AuthService)
+	authHandler := NewAuthHandler(h.AuthService, h.log)
 	taskHandler := NewTaskHandler(h.TaskService, h.log)
+	projectHandler := NewProjectHandler(h.ProjectService, h.TaskService, h.log)
+	tagHandler := NewTagHandler(h.TagService, h.log)
 
 	api := router.Group("/api")
 	{
-		authHandler.Register(api)
-		taskHandler.Register(api)
+		authHandler.Register(api, requireAuth)
+		taskHandler.Register(api, requireAuth)
+		projectHandler.Register(api, requireAuth)
+		tagHandler.Register(api, requireAuth)
 	}
 
 	return router
 }
+
+// jwks serves the public keys that access tokens may be verified with, so
+// that other services can verify them without the HMAC secret.
+func (h *Handler) jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
+
+// setLocation points the Location header of a response to the resource id
+// created under the collection route being served.
+func setLocation(c *gin.Context, id int) {
+	c.Header("Location", c.FullPath()+"/"+strconv.Itoa(id))
+}
+
+// conditionalJSON writes body as a JSON response validated by a hash of body
+// and by lastModified, unless the If-None-Match or, failing that,
+// If-Modified-Since header of the request shows that the client has it
+// already, in which case the response is an empty 304.
+func conditionalJSON(c *gin.Context, body []byte, lastModified time.Time) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// notModified evaluates the If-None-Match and If-Modified-Since headers of r,
+// comparing entity tags weakly as RFC 9110 requires.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.IsZero() {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// errorResponse writes err as a JSON response, mapping service errors to HTTP
+// statuses.
+func errorResponse(c *gin.Context, log *logrus.Logger, err error) {
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.WithError(err).Error("request failed")
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// errorStatus returns the HTTP status reporting a service error.
+func errorStatus(err error) int {
+	var validationErr service.ValidationError
+	switch {
+	case errors.As(err, &validationErr), errors.Is(err, service.ErrInvalidCursor):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrTaskNotFound), errors.Is(err, service.ErrProjectNotFound),
+		errors.Is(err, service.ErrTagNotFound), errors.Is(err, service.ErrDependencyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrProjectExists), errors.Is(err, service.ErrTagExists),
+		errors.Is(err, service.ErrOpenSubtasks), errors.Is(err, service.ErrTaskBlocked),
+		errors.Is(err, service.ErrParentInTrash), errors.Is(err, service.ErrInvalidTransition),
+		errors.Is(err, service.ErrStatusChanged), errors.Is(err, service.ErrUserExists):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrResyncRequired):
+		return http.StatusGone
+	case errors.Is(err, service.ErrInvalidCredentials),
+		errors.Is(err, service.ErrInvalidRefreshToken), errors.Is(err, service.ErrRefreshTokenReused):
+		return http.StatusUnauthorized
+	case errors.As(err, new(*service.LoginThrottledError)):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrBatchAborted):
+		return http.StatusFailedDependency
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"tasklist/internal/repository"
+	"tasklist/internal/service"
+	"tasklist/pkg/auth"
+	"tasklist/pkg/config"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		JwtAlgorithm:       config.JwtHS256,
+		JwtSecret:          "test-secret",
+		JwtIssuer:          "tasklist",
+		JwtAudience:        "tasklist",
+		JwtTtlMin:          60,
+		RefreshTtlDays:     30,
+		PasswordMinLength:  8,
+		PasswordMinClasses: 2,
+		BcryptCost:         4,
+		LoginUserAttempts:  2,
+		LoginIPAttempts:    20,
+		LoginBackoffSec:    30,
+		LoginMaxLockoutSec: 900,
+		LoginWindowMin:     60,
+	}
+}
+
+// testServer serves the API from the memory backend, with a user logged in.
+type testServer struct {
+	router *gin.Engine
+	token  string
+}
+
+const testPassword = "correct-horse-9"
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+
+	cfg := testConfig()
+	keys, err := auth.NewKeyset(cfg)
+	if err != nil {
+		t.Fatalf("NewKeyset failed: %v", err)
+	}
+	repo := repository.NewMemoryRepository()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	svc := service.NewService(repo, repo.LoginRepo, keys, cfg, log)
+
+	resp, err := svc.AuthService.Register(context.Background(), "alice", testPassword)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	return &testServer{router: NewHandler(svc, keys, log).Init(), token: resp.Token}
+}
+
+// do serves a request as the logged-in user. Headers are given as name and
+// value pairs; a JSON body gets a JSON content type unless one is given.
+func (s *testServer) do(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// mustDo serves a request that must answer status.
+func (s *testServer) mustDo(t *testing.T, status int, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := s.do(method, path, body, headers...)
+	if w.Code != status {
+		t.Fatalf("%s %s = %d %s, want %d", method, path, w.Code, w.Body, status)
+	}
+	return w
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{service.ValidationError("bad"), http.StatusBadRequest},
+		{service.ErrTaskNotFound, http.StatusNotFound},
+		{service.ErrVersionMismatch, http.StatusPreconditionFailed},
+		{service.ErrResyncRequired, http.StatusGone},
+		{&service.LoginThrottledError{}, http.StatusTooManyRequests},
+		{io.EOF, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := errorStatus(tt.err); got != tt.want {
+			t.Errorf("errorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestJWKSEndpoint(t *testing.T) {
+	s := newTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") == "" {
+		t.Fatalf("GET /.well-known/jwks.json = %d with Cache-Control %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+	// HMAC secrets are never published.
+	if got := strings.TrimSpace(w.Body.String()); got != `{"keys":[]}` {
+		t.Errorf("JWKS of an HS256 keyset = %s, want no keys", got)
+	}
+}
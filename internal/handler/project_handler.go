@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tasklist/internal/models"
+	"tasklist/internal/service"
+)
+
+type ProjectHandler struct {
+	svc   service.Project
+	tasks service.Task
+	log   *logrus.Logger
+}
+
+func NewProjectHandler(svc service.Project, tasks service.Task, log *logrus.Logger) *ProjectHandler {
+	return &ProjectHandler{svc: svc, tasks: tasks, log: log}
+}
+
+func (h *ProjectHandler) Register(rg *gin.RouterGroup, requireAuth gin.HandlerFunc) {
+	projectGroup := rg.Group("/projects", requireAuth)
+	{
+		projectGroup.POST("", h.Create)
+		projectGroup.GET("", h.List)
+		projectGroup.GET("/:id", h.GetByID)
+		projectGroup.GET("/:id/tasks", h.ListTasks)
+		projectGroup.PUT("/:id", h.Update)
+		projectGroup.DELETE("/:id", h.Delete)
+	}
+}
+
+// Create godoc
+// @Summary      Create project
+// @Description  Create a new project to group tasks
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        input  body  models.ProjectRequest  true  "Project data"
+// @Success      201  {object}  models.Project
+// @Header       201  {string}  Location  "URL of the new project"
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /projects [post]
+func (h *ProjectHandler) Create(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	var req models.ProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.svc.Create(c, userId, req.Name)
+	if err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	setLocation(c, project.ID)
+	c.JSON(http.StatusCreated, project)
+}
+
+// List godoc
+// @Summary      Get projects
+// @Description  Get all projects of authenticated user
+// @Tags         projects
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   models.Project
+// @Failure      401  {object}  map[string]string
+// @Router       /projects [get]
+func (h *ProjectHandler) List(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	projects, err := h.svc.List(c, userId)
+	if err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, projects)
+}
+
+// GetByID godoc
+// @Summary      Get project by ID
+// @Description  Get a single project by its ID
+// @Tags         projects
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  models.Project
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /projects/{id} [get]
+func (h *ProjectHandler) GetByID(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	project, err := h.svc.GetByID(c, id, userId)
+	if err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// ListTasks godoc
+// @Summary      Get project tasks
+// @Description  Get a page of the tasks in a project; accepts the same filters as GET /tasks
+// @Tags         projects
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path   int     true   "Project ID"
+// @Param        limit            query  int     false  "Page size (default 50, max 200)"
+// @Param        after            query  string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        status           query  []string  false  "Only tasks in these statuses (repeatable)"  collectionFormat(multi)
+// @Param        completed        query  bool    false  "Filter by completion: done or not done"
+// @Param        overdue          query  bool    false  "Filter by overdue flag"
+// @Param        tag              query  []string  false  "Only tasks carrying these tags (repeatable)"  collectionFormat(multi)
+// @Param        tag_mode         query  string  false  "all (default) or any of the given tags"
+// @Param        sort             query  string  false  "Sort field, as for GET /tasks (default -id)"
+// @Success      200  {object}  models.TaskList
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /projects/{id}/tasks [get]
+func (h *ProjectHandler) ListTasks(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	var params models.TaskListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.svc.GetByID(c, id, userId); err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	params.ProjectID = &id
+	tasks, err := h.tasks.List(c, userId, params)
+	if err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// Update godoc
+// @Summary      Rename project
+// @Description  Rename a project
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path      int                   true  "Project ID"
+// @Param        input  body      models.ProjectRequest  true  "Project data"
+// @Success      200    {object}  models.Project
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      404    {object}  map[string]string
+// @Failure      409    {object}  map[string]string
+// @Router       /projects/{id} [put]
+func (h *ProjectHandler) Update(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	var req models.ProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.svc.Update(c, id, userId, req.Name)
+	if err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// Delete godoc
+// @Summary      Delete project
+// @Description  Delete a project; mode=inbox (default) keeps its tasks without a project, mode=cascade deletes them too
+// @Tags         projects
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      int     true   "Project ID"
+// @Param        mode  query     string  false  "inbox (default) or cascade"
+// @Success      200   {object}  map[string]string
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Router       /projects/{id} [delete]
+func (h *ProjectHandler) Delete(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	if err := h.svc.Delete(c, id, userId, c.Query("mode")); err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted successfully"})
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tasklist/internal/models"
+	"tasklist/internal/service"
+)
+
+type TagHandler struct {
+	svc service.Tag
+	log *logrus.Logger
+}
+
+func NewTagHandler(svc service.Tag, log *logrus.Logger) *TagHandler {
+	return &TagHandler{svc: svc, log: log}
+}
+
+func (h *TagHandler) Register(rg *gin.RouterGroup, requireAuth gin.HandlerFunc) {
+	tagGroup := rg.Group("/tags", requireAuth)
+	{
+		tagGroup.POST("", h.Create)
+		tagGroup.GET("", h.List)
+		tagGroup.GET("/:id", h.GetByID)
+		tagGroup.PUT("/:id", h.Update)
+		tagGroup.DELETE("/:id", h.Delete)
+	}
+	taskTagGroup := rg.Group("/tasks/:id/tags", requireAuth)
+	{
+		taskTagGroup.POST("/:tag", h.Attach)
+		taskTagGroup.DELETE("/:tag", h.Detach)
+	}
+}
+
+// Create godoc
+// @Summary      Create tag
+// @Description  Create a new tag
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        input  body  models.TagRequest  true  "Tag data"
+// @Success      201  {object}  models.Tag
+// @Header       201  {string}  Location  "URL of the new tag"
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /tags [post]
+func (h *TagHandler) Create(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	var req models.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.svc.Create(c, userId, req.Name)
+	if err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	setLocation(c, tag.ID)
+	c.JSON(http.StatusCreated, tag)
+}
+
+// List godoc
+// @Summary      Get tags
+// @Description  Get all tags of authenticated user
+// @Tags         tags
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   models.Tag
+// @Failure      401  {object}  map[string]string
+// @Router       /tags [get]
+func (h *TagHandler) List(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	tags, err := h.svc.List(c, userId)
+	if err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// GetByID godoc
+// @Summary      Get tag by ID
+// @Description  Get a single tag by its ID
+// @Tags         tags
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Tag ID"
+// @Success      200  {object}  models.Tag
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /tags/{id} [get]
+func (h *TagHandler) GetByID(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag id"})
+		return
+	}
+
+	tag, err := h.svc.GetByID(c, id, userId)
+	if err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
+
+// Update godoc
+// @Summary      Rename tag
+// @Description  Rename a tag; tasks carrying it keep it under the new name
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path      int               true  "Tag ID"
+// @Param        input  body      models.TagRequest  true  "Tag data"
+// @Success      200    {object}  models.Tag
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      404    {object}  map[string]string
+// @Failure      409    {object}  map[string]string
+// @Router       /tags/{id} [put]
+func (h *TagHandler) Update(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag id"})
+		return
+	}
+
+	var req models.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.svc.Update(c, id, userId, req.Name)
+	if err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
+
+// Delete godoc
+// @Summary      Delete tag
+// @Description  Delete a tag and remove it from every task
+// @Tags         tags
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Tag ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /tags/{id} [delete]
+func (h *TagHandler) Delete(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag id"})
+		return
+	}
+
+	if err := h.svc.Delete(c, id, userId); err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted successfully"})
+}
+
+// Attach godoc
+// @Summary      Tag task
+// @Description  Add a tag to a task, creating the tag if it does not exist yet
+// @Tags         tags
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int     true  "Task ID"
+// @Param        tag  path      string  true  "Tag name"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /tasks/{id}/tags/{tag} [post]
+func (h *TagHandler) Attach(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+
+	if err := h.svc.AttachToTask(c, id, userId, c.Param("tag")); err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "tag added successfully"})
+}
+
+// Detach godoc
+// @Summary      Untag task
+// @Description  Remove a tag from a task
+// @Tags         tags
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int     true  "Task ID"
+// @Param        tag  path      string  true  "Tag name"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /tasks/{id}/tags/{tag} [delete]
+func (h *TagHandler) Detach(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+
+	if err := h.svc.DetachFromTask(c, id, userId, c.Param("tag")); err != nil {
+		errorResponse(c, h.log, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "tag removed successfully"})
+}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
-	"tasklist/pkg/middleware"
+	"strings"
+	"tasklist/pkg/mergepatch"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -20,29 +23,45 @@ func NewTaskHandler(svc service.Task, log *logrus.Logger) *TaskHandler {
 	return &TaskHandler{svc: svc, log: log}
 }
 
-func (h *TaskHandler) Register(rg *gin.RouterGroup) {
-	taskGroup := rg.Group("/tasks", middleware.JWTAuth())
+func (h *TaskHandler) Register(rg *gin.RouterGroup, requireAuth gin.HandlerFunc) {
+	taskGroup := rg.Group("/tasks", requireAuth)
 	{
 		taskGroup.POST("", h.Create)
 		taskGroup.GET("", h.List)
+		taskGroup.GET("/search", h.Search)
+		taskGroup.GET("/changes", h.Changes)
+		taskGroup.POST("/batch", h.Batch)
+		taskGroup.GET("/trash", h.ListTrash)
+		taskGroup.DELETE("/trash", h.EmptyTrash)
+		taskGroup.DELETE("/trash/:id", h.Purge)
 		taskGroup.GET("/:id", h.GetByID)
+		taskGroup.GET("/:id/subtasks", h.ListSubtasks)
+		taskGroup.GET("/:id/history", h.History)
+		taskGroup.POST("/:id/dependencies", h.AddDependency)
+		taskGroup.DELETE("/:id/dependencies/:blocker", h.RemoveDependency)
 		taskGroup.POST("/:id/complete", h.Complete)
+		taskGroup.POST("/:id/transition", h.Transition)
+		taskGroup.POST("/:id/restore", h.Restore)
 		taskGroup.PUT("/:id", h.Update)
+		taskGroup.PATCH("/:id", h.Patch)
 		taskGroup.DELETE("/:id", h.Delete)
 	}
 }
 
 // Create godoc
 // @Summary      Create task
-// @Description  Create a new task item
+// @Description  Create a new task item and return it, with its URL in the Location header
 // @Tags         tasks
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
 // @Param        input  body  models.TaskRequest  true  "Task data"
-// @Success      200  {object}  map[string]string
+// @Success      201  {object}  models.Task
+// @Header       201  {string}  Location  "URL of the new task"
+// @Header       201  {string}  ETag      "Version of the task, for If-Match"
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
 // @Router       /tasks [post]
 func (h *TaskHandler) Create(c *gin.Context) {
 	userId := c.GetInt(models.UserCtxKey)
@@ -52,52 +71,305 @@ func (h *TaskHandler) Create(c *gin.Context) {
 		return
 	}
 
-	err := h.svc.Create(c, userId, req)
+	task, err := h.svc.Create(c, userId, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "task created successfully"})
+	setLocation(c, task.ID)
+	setETag(c, task)
+	c.JSON(http.StatusCreated, task)
+}
+
+// batchResult is the outcome of one batch operation, with the status the
+// operation would have had as a request of its own.
+type batchResult struct {
+	Status int          `json:"status" example:"201"`
+	Task   *models.Task `json:"task,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// batchStatuses are the statuses of successful batch operations.
+var batchStatuses = map[string]int{
+	models.BatchCreate:   http.StatusCreated,
+	models.BatchUpdate:   http.StatusOK,
+	models.BatchComplete: http.StatusOK,
+	models.BatchDelete:   http.StatusNoContent,
+}
+
+// Batch godoc
+// @Summary      Run task operations in bulk
+// @Description  Run up to 500 create, update, complete and delete operations in order, returning a result for each with the status it would have had as a request of its own. Update, complete and delete take the task id; create and update take the task. By default the operations share one transaction: if any fails, none is kept, the response has the status of the failed operation and the other operations report 424. With partial=true each operation runs on its own and the response is 200 whatever their results
+// @Tags         tasks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        input  body  models.BatchRequest  true  "Operations"
+// @Success      200  {object}  batchResponse
+// @Failure      400  {object}  batchResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  batchResponse
+// @Failure      409  {object}  batchResponse
+// @Router       /tasks/batch [post]
+func (h *TaskHandler) Batch(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	var req models.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.svc.Batch(c, userId, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	resp := batchResponse{Results: make([]batchResult, len(results))}
+	for i, result := range results {
+		if result.Err == nil {
+			resp.Results[i] = batchResult{Status: batchStatuses[req.Operations[i].Op], Task: result.Task}
+			continue
+		}
+		resp.Results[i] = batchResult{Status: errorStatus(result.Err), Error: result.Err.Error()}
+		if resp.Results[i].Status == http.StatusInternalServerError {
+			h.log.WithError(result.Err).Error("batch operation failed")
+		}
+		if !req.Partial && !errors.Is(result.Err, service.ErrBatchAborted) {
+			status = resp.Results[i].Status
+		}
+	}
+	c.JSON(status, resp)
 }
 
 // Complete godoc
 // @Summary      Complete task
-// @Description  Complete task
+// @Description  Move a task to done, as POST /tasks/{id}/transition does. With subtasks=require (default) it fails while any subtask is open; subtasks=cascade completes them too. Completing a recurring task creates its next occurrence; completing it again after reopening it does not create another. A task blocked by open tasks is only completed with force=true
 // @Tags         tasks
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id   path      int  true  "Task ID"
+// @Param        id        path      int     true   "Task ID"
+// @Param        subtasks  query     string  false  "require (default) or cascade"
+// @Param        force     query     bool    false  "Complete the task even while it is blocked"
 // @Success      200  {object}  map[string]string
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
 // @Router       /tasks/{id}/complete [post]
 func (h *TaskHandler) Complete(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
 	id, err := strconv.Atoi(c.Param("id"))
-	err = h.svc.Complete(c, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+
+	var opts models.CompleteOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.Complete(c, id, userId, opts); err != nil {
+		h.error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "task completed successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "task completed successfully"})
+}
+
+// Transition godoc
+// @Summary      Change task status
+// @Description  Move a task to another status. todo, in_progress and blocked may move to any status; done and cancelled tasks are reopened by moving them to todo or in_progress. Closing a task (done or cancelled) fails while any subtask is open unless subtasks=cascade, which closes them with the same status. Moving a task blocked by open tasks to done needs force=true
+// @Tags         tasks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path  int                       true  "Task ID"
+// @Param        input  body  models.TransitionRequest  true  "New status"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /tasks/{id}/transition [post]
+func (h *TaskHandler) Transition(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+	var req models.TransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.Transition(c, id, userId, req); err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "task status changed successfully"})
 }
 
 // List godoc
 // @Summary      Get tasks
-// @Description  Get all tasks for authenticated user
+// @Description  Get a page of tasks for authenticated user. The response carries an ETag and a Last-Modified time; sending them back in If-None-Match or If-Modified-Since returns an empty 304 while the page is unchanged. If-None-Match is exact, while Last-Modified follows changes to the tasks and their tags, but not whether their blockers are still open
 // @Tags         tasks
 // @Produce      json
 // @Security     BearerAuth
-// @Success      200  {array}  models.Task
+// @Param        limit            query  int     false  "Page size (default 50, max 200)"
+// @Param        after            query  string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        status           query  []string  false  "Only tasks in these statuses (repeatable)"  collectionFormat(multi)
+// @Param        completed        query  bool    false  "Filter by completion: done or not done"
+// @Param        overdue          query  bool    false  "Filter by overdue flag"
+// @Param        deadline_before  query  string  false  "Only tasks due before this RFC 3339 time"
+// @Param        deadline_after   query  string  false  "Only tasks due after this RFC 3339 time"
+// @Param        project_id       query  int     false  "Only tasks in this project"
+// @Param        parent_id        query  int     false  "Only direct subtasks of this task"
+// @Param        tag              query  []string  false  "Only tasks carrying these tags (repeatable)"  collectionFormat(multi)
+// @Param        tag_mode         query  string  false  "all (default) or any of the given tags"
+// @Param        sort             query  string  false  "Sort field: id, deadline, title, priority, created or updated; prefix with - for descending (default -id)"
+// @Param        If-None-Match      header  string  false  "ETag of the page as last read"
+// @Param        If-Modified-Since  header  string  false  "Last-Modified time of the page as last read"
+// @Success      200  {object}  models.TaskList
+// @Header       200  {string}  ETag           "Hash of the page"
+// @Header       200  {string}  Last-Modified  "Time of the latest change to a task"
+// @Success      304
+// @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Router       /tasks [get]
 func (h *TaskHandler) List(c *gin.Context) {
 	userId := c.GetInt(models.UserCtxKey)
-	tasks, err := h.svc.List(c, userId)
+	var params models.TaskListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks, err := h.svc.List(c, userId, params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.error(c, err)
+		return
+	}
+	body, err := json.Marshal(tasks)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	lastModified, err := h.svc.LastModified(c, userId)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	conditionalJSON(c, body, lastModified)
+}
+
+// Changes godoc
+// @Summary      Get task changes
+// @Description  Get the tasks created, updated or moved to the trash since a sync token, oldest change first, for clients keeping a copy of the task list. Tasks in the trash or purged from it are reported in deleted, and restored tasks as updated. Start without since, then pass the sync_token of each response to the next request; has_more means more changes are waiting. Purged tasks are remembered for a retention period; a sync_token older than that fails with 410, after which the client should fetch the full list again and start over without since
+// @Tags         tasks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        since  query  string  false  "sync_token of the previous response"
+// @Param        limit  query  int     false  "Maximum number of changes (default 50, max 200)"
+// @Success      200  {object}  models.TaskChanges
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      410  {object}  map[string]string
+// @Router       /tasks/changes [get]
+func (h *TaskHandler) Changes(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	changes, err := h.svc.Changes(c, userId, c.Query("since"), limit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+// ListSubtasks godoc
+// @Summary      Get subtasks
+// @Description  Get a page of the direct subtasks of a task; accepts the same filters as GET /tasks
+// @Tags         tasks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id         path   int     true   "Task ID"
+// @Param        limit      query  int     false  "Page size (default 50, max 200)"
+// @Param        after      query  string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        status     query  []string  false  "Only subtasks in these statuses (repeatable)"  collectionFormat(multi)
+// @Param        completed  query  bool    false  "Filter by completion: done or not done"
+// @Param        sort       query  string  false  "Sort field, as for GET /tasks (default -id)"
+// @Success      200  {object}  models.TaskList
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /tasks/{id}/subtasks [get]
+func (h *TaskHandler) ListSubtasks(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+	var params models.TaskListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks, err := h.svc.ListSubtasks(c, id, userId, params)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// Search godoc
+// @Summary      Search tasks
+// @Description  Full-text search over the authenticated user's tasks
+// @Tags         tasks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        q      query  string  true   "Search query"
+// @Param        limit  query  int     false  "Maximum number of results (default 50, max 200)"
+// @Success      200  {array}   models.Task
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /tasks/search [get]
+func (h *TaskHandler) Search(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	tasks, err := h.svc.Search(c, userId, c.Query("q"), limit)
+	if err != nil {
+		h.error(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, tasks)
@@ -111,6 +383,7 @@ func (h *TaskHandler) List(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        id   path      int  true  "Task ID"
 // @Success      200  {object}  models.Task
+// @Header       200  {string}  ETag  "Version of the task, for If-Match"
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
@@ -125,26 +398,59 @@ func (h *TaskHandler) GetByID(c *gin.Context) {
 
 	task, err := h.svc.GetByID(c, id, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+		h.error(c, err)
 		return
 	}
 
+	setETag(c, task)
 	c.JSON(http.StatusOK, task)
 }
 
+// History godoc
+// @Summary      Get task history
+// @Description  Get the changes made to a task, oldest first, with who made them; also available for tasks in the trash
+// @Tags         tasks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Task ID"
+// @Success      200  {array}   models.TaskEvent
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /tasks/{id}/history [get]
+func (h *TaskHandler) History(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+
+	events, err := h.svc.History(c, id, userId)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
 // Update godoc
 // @Summary      Update task
-// @Description  Update task by its ID
+// @Description  Replace the editable fields of a task by its ID; omitted fields are cleared or reset to their defaults. Returns the updated task. If-Match must hold the ETag of the task as last read, or * to overwrite any version
 // @Tags         tasks
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id     path      int          true  "Task ID"
-// @Param        input  body      models.TaskRequest  true  "Task data"
-// @Success      200    {object}  models.TaskRequest
-// @Failure      400    {object}  map[string]string
-// @Failure      401    {object}  map[string]string
-// @Failure      404    {object}  map[string]string
+// @Param        id        path      int                 true  "Task ID"
+// @Param        If-Match  header    string              true  "ETag of the task"
+// @Param        input     body      models.TaskRequest  true  "Task data"
+// @Success      200       {object}  models.Task
+// @Header       200       {string}  ETag  "New version of the task"
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      412       {object}  map[string]string
+// @Failure      428       {object}  map[string]string
 // @Router       /tasks/{id} [put]
 func (h *TaskHandler) Update(c *gin.Context) {
 	userId := c.GetInt(models.UserCtxKey)
@@ -154,23 +460,146 @@ func (h *TaskHandler) Update(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var req models.TaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.svc.Update(c, id, userId, req); err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+	task, err := h.svc.Update(c, id, userId, version, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	setETag(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+// Patch godoc
+// @Summary      Patch task
+// @Description  Change some editable fields of a task with a JSON Merge Patch (RFC 7396): fields left out keep their values, and null clears a field or resets it to its default. Returns the updated task. If-Match must hold the ETag of the task as last read, or * to patch any version
+// @Tags         tasks
+// @Accept       application/merge-patch+json
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path      int                 true  "Task ID"
+// @Param        If-Match  header    string              true  "ETag of the task"
+// @Param        input     body      models.TaskRequest  true  "Fields to change"
+// @Success      200       {object}  models.Task
+// @Header       200       {string}  ETag  "New version of the task"
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      412       {object}  map[string]string
+// @Failure      415       {object}  map[string]string
+// @Failure      428       {object}  map[string]string
+// @Router       /tasks/{id} [patch]
+func (h *TaskHandler) Patch(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+	if ct := c.ContentType(); ct != mergepatch.ContentType && ct != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "content type must be " + mergepatch.ContentType})
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "task updated successfully"})
+	task, err := h.svc.Patch(c, id, userId, version, patch)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	setETag(c, task)
+	c.JSON(http.StatusOK, task)
 }
 
 // Delete godoc
 // @Summary      Delete task
-// @Description  Delete a task by its ID
+// @Description  Move a task and its subtasks to the trash, from which they can be restored until purged. If-Match must hold the ETag of the task as last read, or * to delete any version
+// @Tags         tasks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path      int     true  "Task ID"
+// @Param        If-Match  header    string  true  "ETag of the task"
+// @Success      200       {object}  map[string]string
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      412       {object}  map[string]string
+// @Failure      428       {object}  map[string]string
+// @Router       /tasks/{id} [delete]
+func (h *TaskHandler) Delete(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Delete(c, id, userId, version); err != nil {
+		h.error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted successfully"})
+}
+
+// ListTrash godoc
+// @Summary      Get deleted tasks
+// @Description  Get a page of the tasks in the trash; accepts the same filters as GET /tasks
+// @Tags         tasks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        limit       query  int     false  "Page size (default 50, max 200)"
+// @Param        after       query  string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        project_id  query  int     false  "Only tasks in this project"
+// @Param        sort        query  string  false  "Sort field, as for GET /tasks (default -id)"
+// @Success      200  {object}  models.TaskList
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /tasks/trash [get]
+func (h *TaskHandler) ListTrash(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	var params models.TaskListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks, err := h.svc.ListTrash(c, userId, params)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// Restore godoc
+// @Summary      Restore task
+// @Description  Take a task out of the trash, along with the subtasks deleted with it; fails while its parent task is in the trash
 // @Tags         tasks
 // @Produce      json
 // @Security     BearerAuth
@@ -179,18 +608,164 @@ func (h *TaskHandler) Update(c *gin.Context) {
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
-// @Router       /tasks/{id} [delete]
-func (h *TaskHandler) Delete(c *gin.Context) {
+// @Failure      409  {object}  map[string]string
+// @Router       /tasks/{id}/restore [post]
+func (h *TaskHandler) Restore(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
 		return
 	}
 
-	if err := h.svc.Delete(c, id); err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+	if err := h.svc.Restore(c, id, userId); err != nil {
+		h.error(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "task restored successfully"})
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "deleted successfully"})
+// Purge godoc
+// @Summary      Purge task
+// @Description  Permanently delete a task in the trash, along with its subtasks
+// @Tags         tasks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Task ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /tasks/trash/{id} [delete]
+func (h *TaskHandler) Purge(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+
+	if err := h.svc.Purge(c, id, userId); err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "task purged successfully"})
+}
+
+// EmptyTrash godoc
+// @Summary      Empty trash
+// @Description  Permanently delete every task in the trash
+// @Tags         tasks
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Router       /tasks/trash [delete]
+func (h *TaskHandler) EmptyTrash(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	purged, err := h.svc.EmptyTrash(c, userId)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "trash emptied successfully", "purged": purged})
+}
+
+// AddDependency godoc
+// @Summary      Add task dependency
+// @Description  Mark a task as blocked by another task until that one is completed
+// @Tags         tasks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path  int                       true  "Task ID"
+// @Param        input  body  models.DependencyRequest  true  "Blocking task"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /tasks/{id}/dependencies [post]
+func (h *TaskHandler) AddDependency(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+	var req models.DependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.AddDependency(c, id, req.BlockedBy, userId); err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "dependency added successfully"})
+}
+
+// RemoveDependency godoc
+// @Summary      Remove task dependency
+// @Description  Stop a task from waiting on another task
+// @Tags         tasks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int  true  "Task ID"
+// @Param        blocker  path  int  true  "Blocking task ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /tasks/{id}/dependencies/{blocker} [delete]
+func (h *TaskHandler) RemoveDependency(c *gin.Context) {
+	userId := c.GetInt(models.UserCtxKey)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+	blockerId, err := strconv.Atoi(c.Param("blocker"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid blocking task id"})
+		return
+	}
+
+	if err := h.svc.RemoveDependency(c, id, blockerId, userId); err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "dependency removed successfully"})
+}
+
+// setETag sets the ETag of a task response to the version of the task.
+func setETag(c *gin.Context, task *models.Task) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(task.Version)))
+}
+
+// ifMatchVersion returns the task version required by the If-Match header,
+// or 0 for any version. It writes an error response and reports false when
+// the header is missing or cannot match a version, since ETags are strong and
+// a single one is sent per task.
+func ifMatchVersion(c *gin.Context) (int, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+		return 0, false
+	}
+	if header == "*" {
+		return 0, true
+	}
+	if etag, err := strconv.Unquote(header); err == nil {
+		if version, err := strconv.Atoi(etag); err == nil && version > 0 {
+			return version, true
+		}
+	}
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": service.ErrVersionMismatch.Error()})
+	return 0, false
+}
+
+// error writes err as a JSON response.
+func (h *TaskHandler) error(c *gin.Context, err error) {
+	errorResponse(c, h.log, err)
 }
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"tasklist/internal/models"
+)
+
+// createTask creates a task through the API and returns it.
+func (s *testServer) createTask(t *testing.T, body string) models.Task {
+	t.Helper()
+	w := s.mustDo(t, http.StatusCreated, http.MethodPost, "/api/tasks", body)
+	var task models.Task
+	if err := json.Unmarshal(w.Body.Bytes(), &task); err != nil {
+		t.Fatalf("decoding the created task failed: %v", err)
+	}
+	return task
+}
+
+func taskPath(id int) string {
+	return "/api/tasks/" + strconv.Itoa(id)
+}
+
+func TestPatchContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        int
+	}{
+		{"application/merge-patch+json", http.StatusOK},
+		{"application/merge-patch+json; charset=utf-8", http.StatusOK},
+		{"application/json", http.StatusOK},
+		{"text/plain", http.StatusUnsupportedMediaType},
+		{"application/json-patch+json", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			s := newTestServer(t)
+			task := s.createTask(t, `{"title":"draft","deadline":"2026-03-01T09:00:00Z"}`)
+			w := s.do(http.MethodPatch, taskPath(task.ID), `{"title":"final"}`,
+				"Content-Type", tt.contentType, "If-Match", "*")
+			if w.Code != tt.want {
+				t.Fatalf("PATCH as %s = %d %s, want %d", tt.contentType, w.Code, w.Body, tt.want)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var patched models.Task
+			if err := json.Unmarshal(w.Body.Bytes(), &patched); err != nil {
+				t.Fatalf("decoding the patched task failed: %v", err)
+			}
+			if patched.Title != "final" || patched.Deadline == nil || !patched.Deadline.Equal(*task.Deadline) {
+				t.Errorf("patched task = %q due %v; want the title changed and the deadline kept", patched.Title, patched.Deadline)
+			}
+		})
+	}
+}
+
+func TestPatchNullClearsField(t *testing.T) {
+	s := newTestServer(t)
+	task := s.createTask(t, `{"title":"draft","deadline":"2026-03-01T09:00:00Z"}`)
+	w := s.mustDo(t, http.StatusOK, http.MethodPatch, taskPath(task.ID), `{"deadline":null}`,
+		"Content-Type", "application/merge-patch+json", "If-Match", "*")
+	var patched models.Task
+	if err := json.Unmarshal(w.Body.Bytes(), &patched); err != nil {
+		t.Fatalf("decoding the patched task failed: %v", err)
+	}
+	if patched.Title != "draft" || patched.Deadline != nil {
+		t.Errorf("patched task = %q due %v; want the title kept and no deadline", patched.Title, patched.Deadline)
+	}
+}
+
+func TestCreateTask(t *testing.T) {
+	s := newTestServer(t)
+	w := s.mustDo(t, http.StatusCreated, http.MethodPost, "/api/tasks", `{"title":"write report","priority":"high"}`)
+	var task models.Task
+	if err := json.Unmarshal(w.Body.Bytes(), &task); err != nil {
+		t.Fatalf("decoding the created task failed: %v", err)
+	}
+	if task.ID == 0 || task.Title != "write report" || task.Priority != models.PriorityHigh || task.Version != 1 {
+		t.Errorf("created task = %+v", task)
+	}
+	if got, want := w.Header().Get("Location"), taskPath(task.ID); got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+	if got := w.Header().Get("ETag"); got != `"1"` {
+		t.Errorf("ETag = %q, want \"1\"", got)
+	}
+	s.mustDo(t, http.StatusOK, http.MethodGet, w.Header().Get("Location"), "")
+
+	if w := s.do(http.MethodPost, "/api/tasks", `{"title":""}`); w.Code != http.StatusBadRequest || w.Header().Get("Location") != "" {
+		t.Errorf("invalid task = %d with Location %q, want 400 without", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	requests := []struct {
+		method, body string
+	}{
+		{http.MethodPut, `{"title":"replaced"}`},
+		{http.MethodPatch, `{"title":"patched"}`},
+		{http.MethodDelete, ""},
+	}
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    int
+	}{
+		{"missing", "", http.StatusPreconditionRequired},
+		{"stale", `"1"`, http.StatusPreconditionFailed},
+		{"unquoted", "2", http.StatusPreconditionFailed},
+		{"not a version", `"abc"`, http.StatusPreconditionFailed},
+		{"current", `"2"`, http.StatusOK},
+		{"any", "*", http.StatusOK},
+	}
+	for _, req := range requests {
+		for _, tt := range tests {
+			t.Run(req.method+" "+tt.name, func(t *testing.T) {
+				task := s.createTask(t, `{"title":"draft"}`)
+				path := taskPath(task.ID)
+				s.mustDo(t, http.StatusOK, http.MethodPatch, path, `{"description":"v2"}`, "If-Match", `"1"`)
+
+				var headers []string
+				if tt.ifMatch != "" {
+					headers = []string{"If-Match", tt.ifMatch}
+				}
+				w := s.do(req.method, path, req.body, headers...)
+				if w.Code != tt.want {
+					t.Fatalf("%s with If-Match %q = %d %s, want %d", req.method, tt.ifMatch, w.Code, w.Body, tt.want)
+				}
+				if w.Code == http.StatusOK && req.method != http.MethodDelete && w.Header().Get("ETag") != `"3"` {
+					t.Errorf("ETag after the change = %q, want \"3\"", w.Header().Get("ETag"))
+				}
+				if w.Code >= http.StatusBadRequest {
+					got := s.mustDo(t, http.StatusOK, http.MethodGet, path, "")
+					if got.Header().Get("ETag") != `"2"` {
+						t.Errorf("refused %s changed the task to ETag %s", req.method, got.Header().Get("ETag"))
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestListConditional(t *testing.T) {
+	s := newTestServer(t)
+	task := s.createTask(t, `{"title":"draft"}`)
+	first := s.mustDo(t, http.StatusOK, http.MethodGet, "/api/tasks", "")
+	etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("listing has ETag %q and Last-Modified %q", etag, lastModified)
+	}
+
+	tests := []struct {
+		name    string
+		headers []string
+		want    int
+	}{
+		{"same ETag", []string{"If-None-Match", etag}, http.StatusNotModified},
+		{"weak ETag among others", []string{"If-None-Match", `"other", W/` + etag}, http.StatusNotModified},
+		{"any ETag", []string{"If-None-Match", "*"}, http.StatusNotModified},
+		{"other ETag", []string{"If-None-Match", `"other"`}, http.StatusOK},
+		{"other ETag wins over date", []string{"If-None-Match", `"other"`, "If-Modified-Since", lastModified}, http.StatusOK},
+		{"same date", []string{"If-Modified-Since", lastModified}, http.StatusNotModified},
+		{"earlier date", []string{"If-Modified-Since", "Mon, 05 Jan 2026 09:00:00 GMT"}, http.StatusOK},
+		{"invalid date", []string{"If-Modified-Since", "yesterday"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/api/tasks", "", tt.headers...)
+			if w.Code != tt.want {
+				t.Fatalf("GET with %q = %d, want %d", tt.headers, w.Code, tt.want)
+			}
+			if w.Code == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 has a body: %s", w.Body)
+			}
+			if w.Header().Get("ETag") != etag {
+				t.Errorf("ETag = %q, want %q", w.Header().Get("ETag"), etag)
+			}
+		})
+	}
+
+	s.mustDo(t, http.StatusOK, http.MethodPatch, taskPath(task.ID), `{"title":"final"}`, "If-Match", "*")
+	w := s.mustDo(t, http.StatusOK, http.MethodGet, "/api/tasks", "", "If-None-Match", etag)
+	if w.Header().Get("ETag") == etag {
+		t.Errorf("ETag %s did not change with the listing", etag)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	s := newTestServer(t)
+	task := s.createTask(t, `{"title":"draft"}`)
+	w := s.mustDo(t, http.StatusOK, http.MethodGet, "/api/tasks/changes", "")
+	var changes models.TaskChanges
+	if err := json.Unmarshal(w.Body.Bytes(), &changes); err != nil {
+		t.Fatalf("decoding the changes failed: %v", err)
+	}
+	if len(changes.Tasks) != 1 || changes.SyncToken != "1" {
+		t.Fatalf("changes = %+v, want the task and token 1", changes)
+	}
+
+	s.mustDo(t, http.StatusOK, http.MethodDelete, taskPath(task.ID), "", "If-Match", "*")
+	w = s.mustDo(t, http.StatusOK, http.MethodGet, "/api/tasks/changes?since="+changes.SyncToken, "")
+	if err := json.Unmarshal(w.Body.Bytes(), &changes); err != nil {
+		t.Fatalf("decoding the changes failed: %v", err)
+	}
+	if len(changes.Tasks) != 0 || len(changes.Deleted) != 1 || changes.Deleted[0].ID != task.ID {
+		t.Errorf("changes after delete = %+v, want a tombstone for task %d", changes, task.ID)
+	}
+	s.mustDo(t, http.StatusBadRequest, http.MethodGet, "/api/tasks/changes?since=abc", "")
+}
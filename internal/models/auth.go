@@ -1,13 +1,35 @@
 package models
 
+import "time"
+
 const (
-	UserCtxKey = "user_id"
+	UserCtxKey    = "user_id"
+	SessionCtxKey = "session_id"
 )
 
 type AuthRequest struct {
 	Username string `json:"username" binding:"required" example:"testuser"`
 	Password string `json:"password" binding:"required" example:"secret123"`
 }
+
+// AuthResponse carries a short-lived access token, and the refresh token
+// that renews it through POST /auth/refresh.
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn is the lifetime of Token in seconds.
+	ExpiresIn int `json:"expires_in" example:"3600"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LoginFailures counts the failed logins under a key, such as a username or
+// a client address. LockedUntil is set while the key may not be tried.
+type LoginFailures struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type Project struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ProjectRequest struct {
+	Name string `json:"name" binding:"required" example:"Home renovation"`
+}
+
+// Deleting a project either deletes its tasks too or moves them back to the
+// inbox, the tasks without a project.
+const (
+	ProjectDeleteCascade = "cascade"
+	ProjectDeleteInbox   = "inbox"
+)
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RefreshToken is a stored refresh token, kept as a hash of its value. The
+// tokens of a login session replace one another: each refresh uses a token up
+// and issues the next one.
+type RefreshToken struct {
+	ID        int
+	SessionID string
+	UserID    int
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
@@ -1,16 +1,241 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// priorityRanks orders priorities from least to most pressing.
+var priorityRanks = map[Priority]int{
+	PriorityLow:    0,
+	PriorityNormal: 1,
+	PriorityHigh:   2,
+	PriorityUrgent: 3,
+}
+
+func (p Priority) Valid() bool {
+	_, ok := priorityRanks[p]
+	return ok
+}
+
+// Rank returns the position of p from least to most pressing.
+func (p Priority) Rank() int {
+	return priorityRanks[p]
+}
+
+type TaskStatus string
+
+const (
+	StatusTodo       TaskStatus = "todo"
+	StatusInProgress TaskStatus = "in_progress"
+	StatusBlocked    TaskStatus = "blocked"
+	StatusDone       TaskStatus = "done"
+	StatusCancelled  TaskStatus = "cancelled"
+)
+
+func (s TaskStatus) Valid() bool {
+	switch s {
+	case StatusTodo, StatusInProgress, StatusBlocked, StatusDone, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// Closed reports whether a task in status s needs no more work.
+func (s TaskStatus) Closed() bool {
+	return s == StatusDone || s == StatusCancelled
+}
 
 type Task struct {
-	ID        int        `json:"id"`
-	Title     string     `json:"title"`
-	Completed bool       `json:"completed"`
-	Deadline  *time.Time `json:"deadline,omitempty"`
-	IsOverdue bool       `json:"is_overdue"`
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Priority    Priority   `json:"priority"`
+	Status      TaskStatus `json:"status" enums:"todo,in_progress,blocked,done,cancelled"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+	IsOverdue   bool       `json:"is_overdue"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// Version is incremented by every change to the task, and is sent as
+	// its ETag.
+	Version int `json:"version" example:"1"`
+	// StatusChangedAt is when the task last changed status, and CompletedAt
+	// when it was done, if it is.
+	StatusChangedAt time.Time  `json:"status_changed_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+	ProjectID       *int       `json:"project_id,omitempty"`
+	ParentID        *int       `json:"parent_id,omitempty"`
+	Recurrence      string     `json:"recurrence,omitempty"`
+	// NextOccurrenceID is the task created by completing this recurring
+	// one, so that completing it again creates no other.
+	NextOccurrenceID *int     `json:"-"`
+	Tags             []string `json:"tags"`
+	// BlockedBy lists the tasks that must be done first. IsBlocked is set
+	// while any of them is still open, whatever the status of the task.
+	BlockedBy []int `json:"blocked_by"`
+	IsBlocked bool  `json:"is_blocked"`
 }
 
 type TaskRequest struct {
-	Title    string     `json:"title"`
-	Deadline *time.Time `json:"deadline,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Priority    Priority   `json:"priority,omitempty" enums:"low,normal,high,urgent"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+	ProjectID   *int       `json:"project_id,omitempty"`
+	ParentID    *int       `json:"parent_id,omitempty"`
+	// Recurrence is daily, weekly, monthly, yearly or an RRULE such as
+	// FREQ=WEEKLY;BYDAY=MO,WE. Recurring tasks need a deadline.
+	Recurrence string `json:"recurrence,omitempty" example:"FREQ=WEEKLY;BYDAY=FR"`
+}
+
+type DependencyRequest struct {
+	BlockedBy int `json:"blocked_by" binding:"required" example:"42"`
+}
+
+// Completing a task with open subtasks either fails or completes them too.
+const (
+	SubtasksRequire = "require"
+	SubtasksCascade = "cascade"
+)
+
+// CompleteOptions controls how a task is completed. Subtasks is one of the
+// Subtasks* modes; Force completes a task even while it is blocked.
+type CompleteOptions struct {
+	Subtasks string `form:"subtasks"`
+	Force    bool   `form:"force"`
+}
+
+// TransitionRequest moves a task to another status. Subtasks and Force apply
+// when closing a task, as for CompleteOptions: open subtasks are closed with
+// the same status under SubtasksCascade, and only done needs Force while the
+// task is blocked.
+type TransitionRequest struct {
+	Status   TaskStatus `json:"status" binding:"required" enums:"todo,in_progress,blocked,done,cancelled" example:"in_progress"`
+	Subtasks string     `json:"subtasks,omitempty" enums:"require,cascade"`
+	Force    bool       `json:"force,omitempty"`
+}
+
+// Batch operation kinds.
+const (
+	BatchCreate   = "create"
+	BatchUpdate   = "update"
+	BatchComplete = "complete"
+	BatchDelete   = "delete"
+)
+
+// BatchRequest runs several task operations in order. They share one
+// transaction and fail together, unless Partial runs each on its own.
+type BatchRequest struct {
+	Partial    bool             `json:"partial,omitempty"`
+	Operations []BatchOperation `json:"operations" binding:"required"`
+}
+
+// BatchOperation is one operation of a BatchRequest. Create and update take
+// Task; update, complete and delete take the ID of the task. Update and
+// delete fail unless the task is at Version, when it is set, as If-Match does
+// for single requests. Subtasks and Force apply to complete, as for
+// CompleteOptions.
+type BatchOperation struct {
+	Op       string       `json:"op" binding:"required" enums:"create,update,complete,delete"`
+	ID       int          `json:"id,omitempty" example:"42"`
+	Version  int          `json:"version,omitempty" example:"3"`
+	Task     *TaskRequest `json:"task,omitempty"`
+	Subtasks string       `json:"subtasks,omitempty" enums:"require,cascade"`
+	Force    bool         `json:"force,omitempty"`
+}
+
+const (
+	TagModeAll = "all"
+	TagModeAny = "any"
+)
+
+const (
+	TaskSortID       = "id"
+	TaskSortDeadline = "deadline"
+	TaskSortTitle    = "title"
+	TaskSortPriority = "priority"
+	TaskSortCreated  = "created"
+	TaskSortUpdated  = "updated"
+)
+
+// TaskListParams filters and pages a task listing. Tasks must carry every tag
+// in Tags, or any of them when TagMode is TagModeAny. Sort names a TaskSort*
+// field, prefixed with "-" for descending order. Tasks must be in one of
+// Status, if given; Completed is shorthand for being done or not. Trashed
+// lists deleted tasks instead of live ones.
+type TaskListParams struct {
+	Limit          int          `form:"limit"`
+	After          string       `form:"after"`
+	Status         []TaskStatus `form:"status"`
+	Completed      *bool        `form:"completed"`
+	Overdue        *bool        `form:"overdue"`
+	DeadlineBefore *time.Time   `form:"deadline_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	DeadlineAfter  *time.Time   `form:"deadline_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	ProjectID      *int         `form:"project_id"`
+	ParentID       *int         `form:"parent_id"`
+	Tags           []string     `form:"tag"`
+	TagMode        string       `form:"tag_mode"`
+	Sort           string       `form:"sort"`
+	// Trashed lists trashed tasks instead of live ones, and WithTrashed
+	// lists both.
+	Trashed     bool `form:"-"`
+	WithTrashed bool `form:"-"`
+}
+
+// SortField splits Sort into the field name and direction.
+func (p TaskListParams) SortField() (field string, desc bool) {
+	if strings.HasPrefix(p.Sort, "-") {
+		return p.Sort[1:], true
+	}
+	return p.Sort, false
+}
+
+type TaskList struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int    `json:"total"`
+}
+
+// TaskChanges lists the tasks created, updated or deleted since a sync token,
+// oldest change first. Passing SyncToken back fetches the changes that
+// follow, which may already be waiting when HasMore is set.
+type TaskChanges struct {
+	Tasks     []Task          `json:"tasks"`
+	Deleted   []TaskTombstone `json:"deleted"`
+	SyncToken string          `json:"sync_token"`
+	HasMore   bool            `json:"has_more"`
+}
+
+// TaskTombstone reports a task moved to the trash, or purged from it.
+type TaskTombstone struct {
+	ID        int       `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// TaskChange is the last change to a task, numbered in the order the changes
+// of its user were made. Purged is set instead of Task for a purged task.
+type TaskChange struct {
+	Seq    int64
+	Task   *Task
+	Purged *TaskTombstone
+}
+
+// TaskChangeLog is a page of the changes to the tasks of a user, oldest
+// first. Latest numbers the last change made when the page was read, and
+// Horizon the last change of the tombstones dropped since; changes up to it
+// can no longer all be reported.
+type TaskChangeLog struct {
+	Changes []TaskChange
+	Latest  int64
+	Horizon int64
 }
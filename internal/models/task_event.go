@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type TaskAction string
+
+const (
+	TaskCreated       TaskAction = "created"
+	TaskUpdated       TaskAction = "updated"
+	TaskStatusChanged TaskAction = "status_changed"
+	TaskDeleted       TaskAction = "deleted"
+	TaskRestored      TaskAction = "restored"
+	TaskOverdue       TaskAction = "overdue"
+)
+
+// TaskEvent is an entry in the history of a task. Changes maps the name of
+// each field the event changed to its old and new values.
+type TaskEvent struct {
+	ID     int        `json:"id"`
+	TaskID int        `json:"task_id"`
+	Action TaskAction `json:"action" enums:"created,updated,status_changed,deleted,restored,overdue"`
+	// ActorID is the user who made the change, or nil for changes made by the
+	// server itself, such as marking a task overdue. Actor is their username,
+	// or "system".
+	ActorID   *int                   `json:"actor_id"`
+	Actor     string                 `json:"actor"`
+	Changes   map[string]FieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// SystemActor names the actor of events without an ActorID.
+const SystemActor = "system"
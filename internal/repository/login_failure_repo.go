@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"tasklist/db"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"tasklist/internal/models"
+)
+
+// LoginFailure counts failed logins by key, such as a username or a client
+// address, for throttling password guessing.
+type LoginFailure interface {
+	// Reserve counts a login attempt under key at a time as failed, before
+	// its password is checked, unless key is locked out then. The count
+	// starts over if the previous failure was before since, and lockout gives
+	// how long a count locks key out. It returns the count, and whether the
+	// attempt was counted.
+	Reserve(ctx context.Context, key string, at, since time.Time, lockout func(failures int) time.Duration) (models.LoginFailures, bool, error)
+	// Refund takes back an attempt counted by Reserve, which returned
+	// reserved, along with the lockout it caused.
+	Refund(ctx context.Context, key string, reserved models.LoginFailures) error
+	Reset(ctx context.Context, key string) error
+	// Purge deletes the counts whose last failure was before a time.
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+type LoginFailureRepo struct {
+	db pgConn
+}
+
+func NewLoginFailureRepo(db *db.Database) *LoginFailureRepo {
+	return &LoginFailureRepo{db: db.Pool}
+}
+
+// Reserve locks the row of key, inserted first if need be, so that
+// concurrent attempts are counted one after the other.
+func (r *LoginFailureRepo) Reserve(ctx context.Context, key string, at, since time.Time, lockout func(failures int) time.Duration) (models.LoginFailures, bool, error) {
+	f := models.LoginFailures{Key: key}
+	var reserved bool
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		insert := `INSERT INTO login_failures (login_key, failures, last_failure_at) VALUES ($1, 0, $2)
+			ON CONFLICT (login_key) DO NOTHING`
+		if _, err := tx.Exec(ctx, insert, key, at); err != nil {
+			return err
+		}
+		query := `SELECT failures, last_failure_at, locked_until FROM login_failures WHERE login_key=$1 FOR UPDATE`
+		if err := tx.QueryRow(ctx, query, key).Scan(&f.Failures, &f.LastFailureAt, &f.LockedUntil); err != nil {
+			return err
+		}
+		if reserved = reserveLogin(&f, at, since, lockout); !reserved {
+			return nil
+		}
+		update := `UPDATE login_failures SET failures=$2, last_failure_at=$3, locked_until=$4 WHERE login_key=$1`
+		_, err := tx.Exec(ctx, update, key, f.Failures, f.LastFailureAt, f.LockedUntil)
+		return err
+	})
+	return f, reserved, err
+}
+
+func (r *LoginFailureRepo) Refund(ctx context.Context, key string, reserved models.LoginFailures) error {
+	query := `UPDATE login_failures SET failures=greatest(failures - 1, 0),
+			locked_until=CASE WHEN locked_until = $2 THEN NULL ELSE locked_until END
+		WHERE login_key=$1`
+	_, err := r.db.Exec(ctx, query, key, reserved.LockedUntil)
+	return err
+}
+
+func (r *LoginFailureRepo) Reset(ctx context.Context, key string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM login_failures WHERE login_key=$1`, key)
+	return err
+}
+
+// reserveLogin counts an attempt at a time into f, unless f is locked out
+// then, and reports whether it did.
+func reserveLogin(f *models.LoginFailures, at, since time.Time, lockout func(failures int) time.Duration) bool {
+	if f.LockedUntil != nil && at.Before(*f.LockedUntil) {
+		return false
+	}
+	if f.LastFailureAt.Before(since) {
+		f.Failures = 0
+	}
+	f.Failures++
+	f.LastFailureAt = at
+	f.LockedUntil = nil
+	if d := lockout(f.Failures); d > 0 {
+		until := at.Add(d)
+		f.LockedUntil = &until
+	}
+	return true
+}
+
+func (r *LoginFailureRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	rows, err := r.db.Exec(ctx, `DELETE FROM login_failures WHERE last_failure_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(rows.RowsAffected()), nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+func TestLoginFailureReserve(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	window := time.Hour
+	// Three failures lock the key out for a minute.
+	lockout := func(failures int) time.Duration {
+		if failures < 3 {
+			return 0
+		}
+		return time.Minute
+	}
+
+	for name, repo := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			logins := repo.LoginRepo
+			reserve := func(at time.Time) (models.LoginFailures, bool) {
+				t.Helper()
+				f, ok, err := logins.Reserve(ctx, "alice", at, at.Add(-window), lockout)
+				if err != nil {
+					t.Fatalf("Reserve failed: %v", err)
+				}
+				return f, ok
+			}
+
+			for i := 1; i <= 3; i++ {
+				f, ok := reserve(start)
+				if !ok || f.Failures != i {
+					t.Fatalf("attempt %d counted %v with %d failures", i, ok, f.Failures)
+				}
+			}
+			other, _, err := logins.Reserve(ctx, "bob", start, start.Add(-window), lockout)
+			if err != nil || other.Failures != 1 {
+				t.Fatalf("another key shares the count: %+v, %v", other, err)
+			}
+
+			locked, ok := reserve(start.Add(30 * time.Second))
+			if ok || locked.LockedUntil == nil || !locked.LockedUntil.Equal(start.Add(time.Minute)) {
+				t.Fatalf("attempt while locked = %+v, %v; want refused until %s", locked, ok, start.Add(time.Minute))
+			}
+
+			f, ok := reserve(start.Add(time.Minute))
+			if !ok || f.Failures != 4 || f.LockedUntil == nil {
+				t.Fatalf("attempt after lockout = %+v, %v; want 4 failures and locked", f, ok)
+			}
+			if err := logins.Refund(ctx, "alice", f); err != nil {
+				t.Fatalf("Refund failed: %v", err)
+			}
+			f, ok = reserve(start.Add(time.Minute))
+			if !ok || f.Failures != 4 {
+				t.Fatalf("attempt after refund = %+v, %v; want 4 failures", f, ok)
+			}
+
+			later := start.Add(time.Minute + window + time.Second)
+			if f, ok := reserve(later); !ok || f.Failures != 1 {
+				t.Fatalf("attempt after the window = %+v, %v; want the count to start over", f, ok)
+			}
+			if err := logins.Reset(ctx, "alice"); err != nil {
+				t.Fatalf("Reset failed: %v", err)
+			}
+			if f, ok := reserve(later); !ok || f.Failures != 1 {
+				t.Fatalf("attempt after Reset = %+v, %v; want 1 failure", f, ok)
+			}
+		})
+	}
+}
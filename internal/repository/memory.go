@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+// memoryStore holds the state shared by the in-memory repositories.
+type memoryStore struct {
+	mu sync.RWMutex
+
+	users      map[int]models.User
+	nextUserID int
+
+	tasks      map[int]memoryTask
+	nextTaskID int
+
+	projects      map[int]memoryProject
+	nextProjectID int
+
+	tags      map[int]memoryTag
+	nextTagID int
+	// taskTags holds the set of tag IDs attached to each task ID.
+	taskTags map[int]map[int]bool
+
+	// dependencies holds the set of blocking task IDs of each task ID.
+	dependencies map[int]map[int]bool
+
+	// taskEvents holds the history of each task ID, oldest first.
+	taskEvents  map[int][]memoryTaskEvent
+	nextEventID int
+
+	refreshTokens      map[int]models.RefreshToken
+	nextRefreshTokenID int
+
+	// logins is shared by every copy of the store, as failed logins are not
+	// counted in transactions.
+	logins *MemoryLoginFailureRepo
+
+	// changeSeqs holds the number of the last change to the tasks of each
+	// user ID, and syncHorizons that of the last tombstone dropped.
+	changeSeqs   map[int]int64
+	syncHorizons map[int]int64
+	// tombstones holds the purged tasks by ID.
+	tombstones map[int]memoryTombstone
+}
+
+type memoryTask struct {
+	userId    int
+	task      models.Task
+	changeSeq int64
+}
+
+type memoryTombstone struct {
+	userId    int
+	tombstone models.TaskTombstone
+	changeSeq int64
+}
+
+// memoryTaskEvent keeps the changes of an event encoded, as the SQL
+// repositories do.
+type memoryTaskEvent struct {
+	event   models.TaskEvent
+	changes []byte
+}
+
+type memoryProject struct {
+	userId  int
+	project models.Project
+}
+
+type memoryTag struct {
+	userId int
+	tag    models.Tag
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		users:    make(map[int]models.User),
+		tasks:    make(map[int]memoryTask),
+		projects: make(map[int]memoryProject),
+		tags:     make(map[int]memoryTag),
+		taskTags: make(map[int]map[int]bool),
+
+		dependencies: make(map[int]map[int]bool),
+		taskEvents:   make(map[int][]memoryTaskEvent),
+
+		refreshTokens: make(map[int]models.RefreshToken),
+		logins:        NewMemoryLoginFailureRepo(),
+
+		changeSeqs:   make(map[int]int64),
+		syncHorizons: make(map[int]int64),
+		tombstones:   make(map[int]memoryTombstone),
+	}
+}
+
+// withinTx runs fn on a copy of the store, holding the lock throughout, and
+// keeps the copy only when fn returns nil.
+//
+// Copying the whole store makes every transaction, and so most writes, cost
+// time in the size of the store while blocking all other requests. That is
+// the price of a rollback that cannot miss a change, which suits the small
+// stores of development and tests the memory backend is meant for.
+func (s *memoryStore) withinTx(fn func(tx *memoryStore) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := s.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.users, s.nextUserID = tx.users, tx.nextUserID
+	s.tasks, s.nextTaskID = tx.tasks, tx.nextTaskID
+	s.projects, s.nextProjectID = tx.projects, tx.nextProjectID
+	s.tags, s.nextTagID, s.taskTags = tx.tags, tx.nextTagID, tx.taskTags
+	s.dependencies = tx.dependencies
+	s.taskEvents, s.nextEventID = tx.taskEvents, tx.nextEventID
+	s.refreshTokens, s.nextRefreshTokenID = tx.refreshTokens, tx.nextRefreshTokenID
+	s.changeSeqs, s.syncHorizons, s.tombstones = tx.changeSeqs, tx.syncHorizons, tx.tombstones
+	return nil
+}
+
+// clone copies the state of the store. The caller must hold the lock.
+func (s *memoryStore) clone() *memoryStore {
+	return &memoryStore{
+		users:         maps.Clone(s.users),
+		nextUserID:    s.nextUserID,
+		tasks:         maps.Clone(s.tasks),
+		nextTaskID:    s.nextTaskID,
+		projects:      maps.Clone(s.projects),
+		nextProjectID: s.nextProjectID,
+		tags:          maps.Clone(s.tags),
+		nextTagID:     s.nextTagID,
+		taskTags:      cloneSets(s.taskTags),
+		dependencies:  cloneSets(s.dependencies),
+		taskEvents:    cloneEvents(s.taskEvents),
+		nextEventID:   s.nextEventID,
+
+		refreshTokens:      maps.Clone(s.refreshTokens),
+		nextRefreshTokenID: s.nextRefreshTokenID,
+
+		logins: s.logins,
+
+		changeSeqs:   maps.Clone(s.changeSeqs),
+		syncHorizons: maps.Clone(s.syncHorizons),
+		tombstones:   maps.Clone(s.tombstones),
+	}
+}
+
+func cloneSets(sets map[int]map[int]bool) map[int]map[int]bool {
+	clone := make(map[int]map[int]bool, len(sets))
+	for id, set := range sets {
+		clone[id] = maps.Clone(set)
+	}
+	return clone
+}
+
+func cloneEvents(events map[int][]memoryTaskEvent) map[int][]memoryTaskEvent {
+	clone := make(map[int][]memoryTaskEvent, len(events))
+	for id, list := range events {
+		clone[id] = slices.Clone(list)
+	}
+	return clone
+}
+
+// withDetails returns task with its tags and dependencies filled in. The
+// caller must hold the lock.
+func (s *memoryStore) withDetails(task models.Task) models.Task {
+	task.Tags = []string{}
+	for tagId := range s.taskTags[task.ID] {
+		task.Tags = append(task.Tags, s.tags[tagId].tag.Name)
+	}
+	sort.Strings(task.Tags)
+
+	task.BlockedBy = []int{}
+	for blockerId := range s.dependencies[task.ID] {
+		if blocker := s.tasks[blockerId].task; blocker.DeletedAt == nil {
+			task.BlockedBy = append(task.BlockedBy, blockerId)
+			task.IsBlocked = task.IsBlocked || !blocker.Status.Closed()
+		}
+	}
+	sort.Ints(task.BlockedBy)
+	return task
+}
+
+// task finds a task of userId that is not in the trash. The caller must hold
+// the lock.
+func (s *memoryStore) task(taskId, userId int) (memoryTask, bool) {
+	t, ok := s.tasks[taskId]
+	if !ok || t.userId != userId || t.task.DeletedAt != nil {
+		return memoryTask{}, false
+	}
+	return t, true
+}
+
+// descendants returns the IDs of the subtasks of taskId at every depth. The
+// caller must hold the lock.
+func (s *memoryStore) descendants(taskId int) []int {
+	var ids []int
+	for queue := []int{taskId}; len(queue) > 0; queue = queue[1:] {
+		for id, t := range s.tasks {
+			if t.task.ParentID != nil && *t.task.ParentID == queue[0] {
+				ids = append(ids, id)
+				queue = append(queue, id)
+			}
+		}
+	}
+	return ids
+}
+
+// nextChange numbers the next change to the tasks of userId. The caller must
+// hold the write lock.
+func (s *memoryStore) nextChange(userId int) int64 {
+	s.changeSeqs[userId]++
+	return s.changeSeqs[userId]
+}
+
+// touch bumps the update time and version of a task and numbers the change.
+// The caller must hold the write lock and store t back.
+func (s *memoryStore) touch(t *memoryTask, now time.Time) {
+	t.task.UpdatedAt = now
+	t.task.Version++
+	t.changeSeq = s.nextChange(t.userId)
+}
+
+// trashTask moves a task and its subtasks to the trash. The caller must hold
+// the write lock.
+func (s *memoryStore) trashTask(taskId int, now time.Time) {
+	for _, id := range append(s.descendants(taskId), taskId) {
+		if t := s.tasks[id]; t.task.DeletedAt == nil {
+			t.task.DeletedAt = &now
+			s.touch(&t, now)
+			s.tasks[id] = t
+		}
+	}
+}
+
+// deleteTask removes a task along with its subtasks, tag links and
+// dependencies. The caller must hold the write lock.
+func (s *memoryStore) deleteTask(taskId int) {
+	now := time.Now().UTC()
+	for _, id := range append(s.descendants(taskId), taskId) {
+		s.buryTask(id, now)
+		for _, blockerIds := range s.dependencies {
+			delete(blockerIds, id)
+		}
+	}
+}
+
+// buryTask removes a task along with its tag links, dependencies and
+// history, leaving a tombstone for syncing clients. The caller must hold the
+// write lock.
+func (s *memoryStore) buryTask(taskId int, now time.Time) {
+	t := s.tasks[taskId]
+	s.tombstones[taskId] = memoryTombstone{
+		userId:    t.userId,
+		tombstone: models.TaskTombstone{ID: taskId, DeletedAt: now},
+		changeSeq: s.nextChange(t.userId),
+	}
+	delete(s.tasks, taskId)
+	delete(s.taskTags, taskId)
+	delete(s.dependencies, taskId)
+	delete(s.taskEvents, taskId)
+}
+
+// tagByName finds a tag of userId by name. The caller must hold the lock.
+func (s *memoryStore) tagByName(userId int, name string) (models.Tag, bool) {
+	for _, t := range s.tags {
+		if t.userId == userId && t.tag.Name == name {
+			return t.tag, true
+		}
+	}
+	return models.Tag{}, false
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func copyInt(n *int) *int {
+	if n == nil {
+		return nil
+	}
+	c := *n
+	return &c
+}
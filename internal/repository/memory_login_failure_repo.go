@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+// MemoryLoginFailureRepo counts failed logins in the memory of one node. It
+// stands alone, so that it can count logins for any other backend.
+type MemoryLoginFailureRepo struct {
+	mu       sync.Mutex
+	failures map[string]models.LoginFailures
+}
+
+func NewMemoryLoginFailureRepo() *MemoryLoginFailureRepo {
+	return &MemoryLoginFailureRepo{failures: make(map[string]models.LoginFailures)}
+}
+
+func (r *MemoryLoginFailureRepo) Reserve(ctx context.Context, key string, at, since time.Time, lockout func(failures int) time.Duration) (models.LoginFailures, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.failures[key]
+	if !ok {
+		f = models.LoginFailures{Key: key}
+	}
+	if !reserveLogin(&f, at, since, lockout) {
+		return f, false, nil
+	}
+	r.failures[key] = f
+	return f, true, nil
+}
+
+func (r *MemoryLoginFailureRepo) Refund(ctx context.Context, key string, reserved models.LoginFailures) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.failures[key]
+	if !ok {
+		return nil
+	}
+	f.Failures = max(f.Failures-1, 0)
+	if f.LockedUntil != nil && reserved.LockedUntil != nil && f.LockedUntil.Equal(*reserved.LockedUntil) {
+		f.LockedUntil = nil
+	}
+	r.failures[key] = f
+	return nil
+}
+
+func (r *MemoryLoginFailureRepo) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.failures, key)
+	return nil
+}
+
+func (r *MemoryLoginFailureRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := 0
+	for key, f := range r.failures {
+		if f.LastFailureAt.Before(before) {
+			delete(r.failures, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+type MemoryProjectRepo struct {
+	store *memoryStore
+}
+
+func NewMemoryProjectRepo(store *memoryStore) *MemoryProjectRepo {
+	return &MemoryProjectRepo{store: store}
+}
+
+func (r *MemoryProjectRepo) Create(ctx context.Context, userId int, name string) (*models.Project, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.nameTaken(userId, name, 0) {
+		return nil, ErrProjectExists
+	}
+	r.store.nextProjectID++
+	now := time.Now().UTC()
+	project := models.Project{ID: r.store.nextProjectID, Name: name, CreatedAt: now, UpdatedAt: now}
+	r.store.projects[project.ID] = memoryProject{userId: userId, project: project}
+	return &project, nil
+}
+
+func (r *MemoryProjectRepo) List(ctx context.Context, userId int) ([]models.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	projects := []models.Project{}
+	for _, p := range r.store.projects {
+		if p.userId == userId {
+			projects = append(projects, p.project)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Name < projects[j].Name })
+	return projects, nil
+}
+
+func (r *MemoryProjectRepo) GetByID(ctx context.Context, projectId, userId int) (*models.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	p, ok := r.store.projects[projectId]
+	if !ok || p.userId != userId {
+		return nil, ErrProjectNotFound
+	}
+	return &p.project, nil
+}
+
+func (r *MemoryProjectRepo) Update(ctx context.Context, projectId, userId int, name string) (*models.Project, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.projects[projectId]
+	if !ok || p.userId != userId {
+		return nil, ErrProjectNotFound
+	}
+	if r.nameTaken(userId, name, projectId) {
+		return nil, ErrProjectExists
+	}
+	p.project.Name = name
+	p.project.UpdatedAt = time.Now().UTC()
+	r.store.projects[projectId] = p
+	return &p.project, nil
+}
+
+func (r *MemoryProjectRepo) Delete(ctx context.Context, projectId, userId int, cascade bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.projects[projectId]
+	if !ok || p.userId != userId {
+		return ErrProjectNotFound
+	}
+	now := time.Now().UTC()
+	for id, t := range r.store.tasks {
+		if t.task.ProjectID == nil || *t.task.ProjectID != projectId {
+			continue
+		}
+		if cascade && t.task.DeletedAt == nil {
+			r.store.trashTask(id, now)
+		}
+		t = r.store.tasks[id]
+		t.task.ProjectID = nil
+		r.store.touch(&t, now)
+		r.store.tasks[id] = t
+	}
+	delete(r.store.projects, projectId)
+	return nil
+}
+
+// nameTaken reports whether userId has a project other than exceptId named
+// name. The caller must hold the lock.
+func (r *MemoryProjectRepo) nameTaken(userId int, name string, exceptId int) bool {
+	for id, p := range r.store.projects {
+		if id != exceptId && p.userId == userId && p.project.Name == name {
+			return true
+		}
+	}
+	return false
+}
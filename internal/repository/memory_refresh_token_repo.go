@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+type MemoryRefreshTokenRepo struct {
+	store *memoryStore
+}
+
+func NewMemoryRefreshTokenRepo(store *memoryStore) *MemoryRefreshTokenRepo {
+	return &MemoryRefreshTokenRepo{store: store}
+}
+
+func (r *MemoryRefreshTokenRepo) Create(ctx context.Context, token models.RefreshToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.nextRefreshTokenID++
+	token.ID = r.store.nextRefreshTokenID
+	token.CreatedAt = time.Now().UTC()
+	r.store.refreshTokens[token.ID] = token
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepo) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, token := range r.store.refreshTokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, ErrRefreshTokenNotFound
+}
+
+func (r *MemoryRefreshTokenRepo) MarkUsed(ctx context.Context, id int) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.refreshTokens[id]
+	if !ok || token.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	token.UsedAt = &now
+	r.store.refreshTokens[id] = token
+	return true, nil
+}
+
+func (r *MemoryRefreshTokenRepo) RevokeSession(ctx context.Context, sessionId string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now().UTC()
+	for id, token := range r.store.refreshTokens {
+		if token.SessionID == sessionId && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.store.refreshTokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepo) SessionActive(ctx context.Context, sessionId string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, token := range r.store.refreshTokens {
+		if token.SessionID == sessionId && token.RevokedAt == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MemoryRefreshTokenRepo) PurgeExpired(ctx context.Context, before time.Time) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	purged := 0
+	for id, token := range r.store.refreshTokens {
+		if token.ExpiresAt.Before(before) {
+			delete(r.store.refreshTokens, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
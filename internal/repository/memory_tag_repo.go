@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+type MemoryTagRepo struct {
+	store *memoryStore
+}
+
+func NewMemoryTagRepo(store *memoryStore) *MemoryTagRepo {
+	return &MemoryTagRepo{store: store}
+}
+
+func (r *MemoryTagRepo) Create(ctx context.Context, userId int, name string) (*models.Tag, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.tagByName(userId, name); ok {
+		return nil, ErrTagExists
+	}
+	tag := r.create(userId, name)
+	return &tag, nil
+}
+
+func (r *MemoryTagRepo) List(ctx context.Context, userId int) ([]models.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tags := []models.Tag{}
+	for _, t := range r.store.tags {
+		if t.userId == userId {
+			tags = append(tags, t.tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (r *MemoryTagRepo) GetByID(ctx context.Context, tagId, userId int) (*models.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	t, ok := r.store.tags[tagId]
+	if !ok || t.userId != userId {
+		return nil, ErrTagNotFound
+	}
+	return &t.tag, nil
+}
+
+func (r *MemoryTagRepo) Update(ctx context.Context, tagId, userId int, name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.tags[tagId]
+	if !ok || t.userId != userId {
+		return ErrTagNotFound
+	}
+	if other, ok := r.store.tagByName(userId, name); ok && other.ID != tagId {
+		return ErrTagExists
+	}
+	t.tag.Name = name
+	r.store.tags[tagId] = t
+	r.touchTaggedTasks(tagId)
+	return nil
+}
+
+func (r *MemoryTagRepo) Delete(ctx context.Context, tagId, userId int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.tags[tagId]
+	if !ok || t.userId != userId {
+		return ErrTagNotFound
+	}
+	r.touchTaggedTasks(tagId)
+	delete(r.store.tags, tagId)
+	for _, tagIds := range r.store.taskTags {
+		delete(tagIds, tagId)
+	}
+	return nil
+}
+
+func (r *MemoryTagRepo) Attach(ctx context.Context, taskId, userId int, name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.touchTask(taskId, userId); err != nil {
+		return err
+	}
+	tag, ok := r.store.tagByName(userId, name)
+	if !ok {
+		tag = r.create(userId, name)
+	}
+	if r.store.taskTags[taskId] == nil {
+		r.store.taskTags[taskId] = make(map[int]bool)
+	}
+	r.store.taskTags[taskId][tag.ID] = true
+	return nil
+}
+
+func (r *MemoryTagRepo) Detach(ctx context.Context, taskId, userId int, name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.task(taskId, userId); !ok {
+		return ErrTaskNotFound
+	}
+	tag, ok := r.store.tagByName(userId, name)
+	if !ok || !r.store.taskTags[taskId][tag.ID] {
+		return ErrTagNotFound
+	}
+	delete(r.store.taskTags[taskId], tag.ID)
+	return r.touchTask(taskId, userId)
+}
+
+// create adds a tag. The caller must hold the write lock.
+func (r *MemoryTagRepo) create(userId int, name string) models.Tag {
+	r.store.nextTagID++
+	tag := models.Tag{ID: r.store.nextTagID, Name: name}
+	r.store.tags[tag.ID] = memoryTag{userId: userId, tag: tag}
+	return tag
+}
+
+// touchTask bumps the update time of a task, failing with ErrTaskNotFound
+// unless userId owns it. The caller must hold the write lock.
+// touchTaggedTasks counts renaming or deleting a tag as a change to the live
+// tasks that have it. The caller must hold the write lock.
+func (r *MemoryTagRepo) touchTaggedTasks(tagId int) {
+	now := time.Now().UTC()
+	for taskId, tagIds := range r.store.taskTags {
+		t, ok := r.store.tasks[taskId]
+		if !tagIds[tagId] || !ok || t.task.DeletedAt != nil {
+			continue
+		}
+		r.store.touch(&t, now)
+		r.store.tasks[taskId] = t
+	}
+}
+
+func (r *MemoryTagRepo) touchTask(taskId, userId int) error {
+	t, ok := r.store.task(taskId, userId)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	r.store.touch(&t, time.Now().UTC())
+	r.store.tasks[taskId] = t
+	return nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+type MemoryTaskEventRepo struct {
+	store *memoryStore
+}
+
+func NewMemoryTaskEventRepo(store *memoryStore) *MemoryTaskEventRepo {
+	return &MemoryTaskEventRepo{store: store}
+}
+
+func (r *MemoryTaskEventRepo) Record(ctx context.Context, events ...models.TaskEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	encoded := make([][]byte, len(events))
+	for i, event := range events {
+		if _, ok := r.store.tasks[event.TaskID]; !ok {
+			return ErrTaskNotFound
+		}
+		changes, err := json.Marshal(event.Changes)
+		if err != nil {
+			return err
+		}
+		encoded[i] = changes
+	}
+
+	now := time.Now().UTC()
+	for i, event := range events {
+		r.store.nextEventID++
+		event.ID = r.store.nextEventID
+		event.ActorID = copyInt(event.ActorID)
+		event.Actor = ""
+		event.Changes = nil
+		event.CreatedAt = now
+		r.store.taskEvents[event.TaskID] = append(r.store.taskEvents[event.TaskID],
+			memoryTaskEvent{event: event, changes: encoded[i]})
+	}
+	return nil
+}
+
+func (r *MemoryTaskEventRepo) History(ctx context.Context, taskId, userId int) ([]models.TaskEvent, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if t, ok := r.store.tasks[taskId]; !ok || t.userId != userId {
+		return nil, ErrTaskNotFound
+	}
+	events := []models.TaskEvent{}
+	for _, e := range r.store.taskEvents[taskId] {
+		event := e.event
+		event.ActorID = copyInt(e.event.ActorID)
+		event.Actor = models.SystemActor
+		if event.ActorID != nil {
+			event.Actor = r.store.users[*event.ActorID].Username
+		}
+		if err := json.Unmarshal(e.changes, &event.Changes); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
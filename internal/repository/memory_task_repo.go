@@ -0,0 +1,481 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+type MemoryTaskRepo struct {
+	store *memoryStore
+}
+
+func NewMemoryTaskRepo(store *memoryStore) *MemoryTaskRepo {
+	return &MemoryTaskRepo{store: store}
+}
+
+func (r *MemoryTaskRepo) Create(ctx context.Context, userId int, task models.TaskRequest) (*models.Task, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.nextTaskID++
+	id := r.store.nextTaskID
+	now := time.Now().UTC()
+	r.store.tasks[id] = memoryTask{
+		userId: userId,
+		task: models.Task{
+			ID:              id,
+			Title:           task.Title,
+			Description:     task.Description,
+			Priority:        task.Priority,
+			Deadline:        copyTime(task.Deadline),
+			ProjectID:       copyInt(task.ProjectID),
+			ParentID:        copyInt(task.ParentID),
+			Recurrence:      task.Recurrence,
+			Status:          models.StatusTodo,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			Version:         1,
+			StatusChangedAt: now,
+		},
+		changeSeq: r.store.nextChange(userId),
+	}
+	created := r.store.withDetails(r.store.tasks[id].task)
+	return &created, nil
+}
+
+func (r *MemoryTaskRepo) SetStatus(ctx context.Context, taskId, userId int, from, to models.TaskStatus, cascade bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current, ok := r.store.task(taskId, userId)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if current.task.Status != from {
+		return ErrStatusChanged
+	}
+	ids := []int{taskId}
+	if cascade {
+		for _, id := range r.store.descendants(taskId) {
+			if d := r.store.tasks[id].task; !d.Status.Closed() && d.DeletedAt == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	now := time.Now().UTC()
+	for _, id := range ids {
+		t := r.store.tasks[id]
+		t.task.Status = to
+		t.task.StatusChangedAt = now
+		t.task.CompletedAt = nil
+		if to == models.StatusDone {
+			t.task.CompletedAt = &now
+		}
+		r.store.touch(&t, now)
+		r.store.tasks[id] = t
+	}
+	return nil
+}
+
+func (r *MemoryTaskRepo) SetNextOccurrence(ctx context.Context, taskId, userId, nextId int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.tasks[taskId]
+	if !ok || t.userId != userId {
+		return ErrTaskNotFound
+	}
+	t.task.NextOccurrenceID = &nextId
+	r.store.tasks[taskId] = t
+	return nil
+}
+
+func (r *MemoryTaskRepo) List(ctx context.Context, userId int, params models.TaskListParams) (*models.TaskList, error) {
+	var cursor *listCursor
+	if params.After != "" {
+		c, err := decodeCursor(params.After)
+		if err != nil {
+			return nil, err
+		}
+		cursor = &c
+	}
+	field, desc := params.SortField()
+	// less reports whether a sorts before b in the requested order.
+	less := func(a, b listCursor) bool {
+		if a.Key != b.Key {
+			return (a.Key < b.Key) != desc
+		}
+		return a.ID != b.ID && (a.ID < b.ID) != desc
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tasks := []models.Task{}
+	total := 0
+	for _, t := range r.store.tasks {
+		if t.userId != userId {
+			continue
+		}
+		task := r.store.withDetails(t.task)
+		if !matchesListParams(task, params) {
+			continue
+		}
+		total++
+		if cursor != nil && !less(*cursor, listCursor{Key: sortKey(task, field), ID: task.ID}) {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return less(
+			listCursor{Key: sortKey(tasks[i], field), ID: tasks[i].ID},
+			listCursor{Key: sortKey(tasks[j], field), ID: tasks[j].ID},
+		)
+	})
+	if len(tasks) > params.Limit+1 {
+		tasks = tasks[:params.Limit+1]
+	}
+	return newTaskList(tasks, total, params), nil
+}
+
+// Search matches every word of query as a case-insensitive substring of the
+// title or description.
+func (r *MemoryTaskRepo) Search(ctx context.Context, userId int, query string, limit int) ([]models.Task, error) {
+	terms := searchTerms(query)
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tasks := []models.Task{}
+	for _, t := range r.store.tasks {
+		if t.userId == userId && t.task.DeletedAt == nil && matchesTerms(terms, t.task.Title, t.task.Description) {
+			tasks = append(tasks, r.store.withDetails(t.task))
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID > tasks[j].ID })
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+	return tasks, nil
+}
+
+func (r *MemoryTaskRepo) ListAll(ctx context.Context) ([]models.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tasks := []models.Task{}
+	for _, t := range r.store.tasks {
+		if !t.task.IsOverdue && !t.task.Status.Closed() && t.task.DeletedAt == nil {
+			tasks = append(tasks, t.task)
+		}
+	}
+	return tasks, nil
+}
+
+func (r *MemoryTaskRepo) GetByID(ctx context.Context, taskId, userId int) (*models.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	t, ok := r.store.task(taskId, userId)
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	task := r.store.withDetails(t.task)
+	return &task, nil
+}
+
+func (r *MemoryTaskRepo) Descendants(ctx context.Context, taskId, userId int) ([]models.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tasks := []models.Task{}
+	if _, ok := r.store.task(taskId, userId); !ok {
+		return tasks, nil
+	}
+	for _, id := range r.store.descendants(taskId) {
+		if task := r.store.tasks[id].task; task.DeletedAt == nil {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+func (r *MemoryTaskRepo) Update(ctx context.Context, taskId, userId, version int, task models.TaskRequest) error {
+	return r.modify(taskId, userId, version, func(t *models.Task) {
+		t.Title = task.Title
+		t.Description = task.Description
+		t.Priority = task.Priority
+		t.Deadline = copyTime(task.Deadline)
+		t.ProjectID = copyInt(task.ProjectID)
+		t.ParentID = copyInt(task.ParentID)
+		t.Recurrence = task.Recurrence
+	})
+}
+
+func (r *MemoryTaskRepo) Delete(ctx context.Context, taskId, userId, version int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.task(taskId, userId)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if version != 0 && version != t.task.Version {
+		return ErrVersionMismatch
+	}
+	r.store.trashTask(taskId, time.Now().UTC())
+	return nil
+}
+
+func (r *MemoryTaskRepo) Restore(ctx context.Context, taskId, userId int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.tasks[taskId]
+	if !ok || t.userId != userId || t.task.DeletedAt == nil {
+		return ErrTaskNotFound
+	}
+	if t.task.ParentID != nil && r.store.tasks[*t.task.ParentID].task.DeletedAt != nil {
+		return ErrParentInTrash
+	}
+	deletedAt := *t.task.DeletedAt
+	now := time.Now().UTC()
+	for _, id := range append(r.store.descendants(taskId), taskId) {
+		if t := r.store.tasks[id]; t.task.DeletedAt != nil && t.task.DeletedAt.Equal(deletedAt) {
+			t.task.DeletedAt = nil
+			r.store.touch(&t, now)
+			r.store.tasks[id] = t
+		}
+	}
+	return nil
+}
+
+func (r *MemoryTaskRepo) Purge(ctx context.Context, taskId, userId int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.tasks[taskId]
+	if !ok || t.userId != userId || t.task.DeletedAt == nil {
+		return ErrTaskNotFound
+	}
+	r.store.deleteTask(taskId)
+	return nil
+}
+
+func (r *MemoryTaskRepo) EmptyTrash(ctx context.Context, userId int) (int, error) {
+	return r.purge(func(t memoryTask) bool { return t.userId == userId })
+}
+
+func (r *MemoryTaskRepo) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	return r.purge(func(t memoryTask) bool { return t.task.DeletedAt.Before(before) })
+}
+
+// purge permanently deletes the trashed tasks matching fn.
+func (r *MemoryTaskRepo) purge(fn func(t memoryTask) bool) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	purged := 0
+	now := time.Now().UTC()
+	for id, t := range r.store.tasks {
+		if t.task.DeletedAt != nil && fn(t) {
+			r.store.buryTask(id, now)
+			purged++
+		}
+	}
+	for _, blockerIds := range r.store.dependencies {
+		for id := range blockerIds {
+			if _, ok := r.store.tasks[id]; !ok {
+				delete(blockerIds, id)
+			}
+		}
+	}
+	return purged, nil
+}
+
+func (r *MemoryTaskRepo) Changes(ctx context.Context, userId int, since int64, limit int) (*models.TaskChangeLog, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	log := &models.TaskChangeLog{Latest: r.store.changeSeqs[userId], Horizon: r.store.syncHorizons[userId]}
+	for _, t := range r.store.tasks {
+		if t.userId == userId && t.changeSeq > since {
+			task := r.store.withDetails(t.task)
+			log.Changes = append(log.Changes, models.TaskChange{Seq: t.changeSeq, Task: &task})
+		}
+	}
+	for _, t := range r.store.tombstones {
+		if t.userId == userId && t.changeSeq > since {
+			tombstone := t.tombstone
+			log.Changes = append(log.Changes, models.TaskChange{Seq: t.changeSeq, Purged: &tombstone})
+		}
+	}
+	log.Changes = sortTaskChanges(log.Changes, limit)
+	return log, nil
+}
+
+// PurgeTombstones raises the horizon of each user to the last change it
+// drops, so that clients that have not synced it are told to start over.
+func (r *MemoryTaskRepo) PurgeTombstones(ctx context.Context, before time.Time) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	purged := 0
+	for id, t := range r.store.tombstones {
+		if t.tombstone.DeletedAt.Before(before) {
+			r.store.syncHorizons[t.userId] = max(r.store.syncHorizons[t.userId], t.changeSeq)
+			delete(r.store.tombstones, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (r *MemoryTaskRepo) AddDependency(ctx context.Context, taskId, blockerId, userId int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.task(taskId, userId)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if _, ok := r.store.task(blockerId, userId); ok {
+		if r.store.dependencies[taskId] == nil {
+			r.store.dependencies[taskId] = make(map[int]bool)
+		}
+		r.store.dependencies[taskId][blockerId] = true
+	}
+	r.store.touch(&t, time.Now().UTC())
+	r.store.tasks[taskId] = t
+	return nil
+}
+
+func (r *MemoryTaskRepo) RemoveDependency(ctx context.Context, taskId, blockerId, userId int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.task(taskId, userId)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if !r.store.dependencies[taskId][blockerId] {
+		return ErrDependencyNotFound
+	}
+	delete(r.store.dependencies[taskId], blockerId)
+	r.store.touch(&t, time.Now().UTC())
+	r.store.tasks[taskId] = t
+	return nil
+}
+
+func (r *MemoryTaskRepo) Blockers(ctx context.Context, taskId, userId int) ([]int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var ids []int
+	if t, ok := r.store.tasks[taskId]; !ok || t.userId != userId {
+		return ids, nil
+	}
+	seen := map[int]bool{}
+	for queue := []int{taskId}; len(queue) > 0; queue = queue[1:] {
+		for blockerId := range r.store.dependencies[queue[0]] {
+			if !seen[blockerId] {
+				seen[blockerId] = true
+				ids = append(ids, blockerId)
+				queue = append(queue, blockerId)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (r *MemoryTaskRepo) MarkOverdued(ctx context.Context, taskId int) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.tasks[taskId]
+	if !ok || t.task.IsOverdue || t.task.Status.Closed() || t.task.DeletedAt != nil {
+		return false, nil
+	}
+	t.task.IsOverdue = true
+	r.store.touch(&t, time.Now().UTC())
+	r.store.tasks[taskId] = t
+	return true, nil
+}
+
+func matchesListParams(task models.Task, params models.TaskListParams) bool {
+	if !params.WithTrashed && (task.DeletedAt != nil) != params.Trashed {
+		return false
+	}
+	if len(params.Status) > 0 && !slices.Contains(params.Status, task.Status) {
+		return false
+	}
+	if params.Completed != nil && (task.Status == models.StatusDone) != *params.Completed {
+		return false
+	}
+	if params.Overdue != nil && task.IsOverdue != *params.Overdue {
+		return false
+	}
+	if params.DeadlineBefore != nil && (task.Deadline == nil || !task.Deadline.Before(*params.DeadlineBefore)) {
+		return false
+	}
+	if params.DeadlineAfter != nil && (task.Deadline == nil || !task.Deadline.After(*params.DeadlineAfter)) {
+		return false
+	}
+	if params.ProjectID != nil && (task.ProjectID == nil || *task.ProjectID != *params.ProjectID) {
+		return false
+	}
+	if params.ParentID != nil && (task.ParentID == nil || *task.ParentID != *params.ParentID) {
+		return false
+	}
+	if len(params.Tags) > 0 {
+		matched := 0
+		for _, tag := range params.Tags {
+			if slices.Contains(task.Tags, tag) {
+				matched++
+			}
+		}
+		if params.TagMode == models.TagModeAny && matched == 0 {
+			return false
+		}
+		if params.TagMode != models.TagModeAny && matched < len(params.Tags) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesTerms(terms []string, fields ...string) bool {
+	text := strings.ToLower(strings.Join(fields, " "))
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// modify applies fn to the task owned by userId under the write lock and bumps
+// its update time and version. Unless version is 0, the task must be at it.
+func (r *MemoryTaskRepo) modify(taskId, userId, version int, fn func(task *models.Task)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.task(taskId, userId)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if version != 0 && version != t.task.Version {
+		return ErrVersionMismatch
+	}
+	fn(&t.task)
+	r.store.touch(&t, time.Now().UTC())
+	r.store.tasks[taskId] = t
+	return nil
+}
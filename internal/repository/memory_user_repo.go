@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+
+	"tasklist/internal/models"
+)
+
+type MemoryUserRepo struct {
+	store *memoryStore
+}
+
+func NewMemoryUserRepo(store *memoryStore) *MemoryUserRepo {
+	return &MemoryUserRepo{store: store}
+}
+
+func (r *MemoryUserRepo) Create(ctx context.Context, user *models.User) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, u := range r.store.users {
+		if u.Username == user.Username {
+			return 0, ErrUserExists
+		}
+	}
+	r.store.nextUserID++
+	created := *user
+	created.ID = r.store.nextUserID
+	r.store.users[created.ID] = created
+	return created.ID, nil
+}
+
+func (r *MemoryUserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, u := range r.store.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (r *MemoryUserRepo) UpdatePassword(ctx context.Context, userId int, password string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u, ok := r.store.users[userId]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.Password = password
+	r.store.users[userId] = u
+	return nil
+}
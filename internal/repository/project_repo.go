@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"tasklist/db"
+
+	"github.com/jackc/pgx/v5"
+
+	"tasklist/internal/models"
+)
+
+var (
+	ErrProjectNotFound = errors.New("project not found")
+	ErrProjectExists   = errors.New("project already exists")
+)
+
+type Project interface {
+	Create(ctx context.Context, userId int, name string) (*models.Project, error)
+	List(ctx context.Context, userId int) ([]models.Project, error)
+	GetByID(ctx context.Context, projectId, userId int) (*models.Project, error)
+	Update(ctx context.Context, projectId, userId int, name string) (*models.Project, error)
+
+	// Delete removes a project along with its tasks when cascade is set, or
+	// moves its tasks to the inbox otherwise.
+	Delete(ctx context.Context, projectId, userId int, cascade bool) error
+}
+
+type ProjectRepo struct {
+	db pgConn
+}
+
+func NewProjectRepo(db *db.Database) *ProjectRepo {
+	return &ProjectRepo{db: db.Pool}
+}
+
+const projectColumns = `id, name, created_at, updated_at`
+
+func scanProject(row rowScanner) (models.Project, error) {
+	var project models.Project
+	err := row.Scan(&project.ID, &project.Name, &project.CreatedAt, &project.UpdatedAt)
+	return project, err
+}
+
+func (r *ProjectRepo) Create(ctx context.Context, userId int, name string) (*models.Project, error) {
+	query := `INSERT INTO projects (user_id, name) VALUES ($1, $2) RETURNING ` + projectColumns
+	project, err := scanProject(r.db.QueryRow(ctx, query, userId, name))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrProjectExists
+		}
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *ProjectRepo) List(ctx context.Context, userId int) ([]models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE user_id=$1 ORDER BY name`
+	rows, err := r.db.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+func (r *ProjectRepo) GetByID(ctx context.Context, projectId, userId int) (*models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE id=$1 and user_id=$2`
+	project, err := scanProject(r.db.QueryRow(ctx, query, projectId, userId))
+	if err != nil {
+		return nil, ErrProjectNotFound
+	}
+	return &project, nil
+}
+
+func (r *ProjectRepo) Update(ctx context.Context, projectId, userId int, name string) (*models.Project, error) {
+	query := `UPDATE projects SET name=$1, updated_at=now() WHERE id=$2 and user_id=$3 RETURNING ` + projectColumns
+	project, err := scanProject(r.db.QueryRow(ctx, query, name, projectId, userId))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrProjectExists
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *ProjectRepo) Delete(ctx context.Context, projectId, userId int, cascade bool) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// Moving tasks explicitly rather than relying on ON DELETE SET NULL
+		// bumps their update time. Cascading moves the tasks and their
+		// subtasks to the trash.
+		tasks := `UPDATE tasks SET project_id=NULL, updated_at=now(), version=version+1 WHERE project_id=$1 and user_id=$2`
+		if cascade {
+			tasks = `UPDATE tasks SET deleted_at=now(), updated_at=now(), version=version+1
+				WHERE id IN (` + subtreeIDs(db.DialectPostgres, "project_id") + `) AND deleted_at IS NULL`
+		}
+		if _, err := tx.Exec(ctx, tasks, projectId, userId); err != nil {
+			return err
+		}
+		rows, err := tx.Exec(ctx, `DELETE FROM projects WHERE id=$1 and user_id=$2`, projectId, userId)
+		if err != nil {
+			return err
+		}
+		if rows.RowsAffected() == 0 {
+			return ErrProjectNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"tasklist/db"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"tasklist/internal/models"
+)
+
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+// RefreshToken stores the refresh tokens of login sessions.
+type RefreshToken interface {
+	Create(ctx context.Context, token models.RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// MarkUsed marks a token used, reporting false if it already was.
+	MarkUsed(ctx context.Context, id int) (bool, error)
+	// RevokeSession revokes every token of a session. SessionActive reports
+	// whether a session has any token left that is not revoked.
+	RevokeSession(ctx context.Context, sessionId string) error
+	SessionActive(ctx context.Context, sessionId string) (bool, error)
+	// PurgeExpired deletes the tokens that expired before a time.
+	PurgeExpired(ctx context.Context, before time.Time) (int, error)
+}
+
+type RefreshTokenRepo struct {
+	db pgConn
+}
+
+func NewRefreshTokenRepo(db *db.Database) *RefreshTokenRepo {
+	return &RefreshTokenRepo{db: db.Pool}
+}
+
+const refreshTokenColumns = `id, session_id, user_id, token_hash, expires_at, created_at, used_at, revoked_at`
+
+func scanRefreshToken(row rowScanner) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := row.Scan(&token.ID, &token.SessionID, &token.UserID, &token.TokenHash, &token.ExpiresAt,
+		&token.CreatedAt, &token.UsedAt, &token.RevokedAt)
+	return &token, err
+}
+
+func (r *RefreshTokenRepo) Create(ctx context.Context, token models.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (session_id, user_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.Exec(ctx, query, token.SessionID, token.UserID, token.TokenHash, token.ExpiresAt)
+	return err
+}
+
+func (r *RefreshTokenRepo) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	query := `SELECT ` + refreshTokenColumns + ` FROM refresh_tokens WHERE token_hash=$1`
+	token, err := scanRefreshToken(r.db.QueryRow(ctx, query, hash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *RefreshTokenRepo) MarkUsed(ctx context.Context, id int) (bool, error) {
+	query := `UPDATE refresh_tokens SET used_at=now() WHERE id=$1 and used_at is null`
+	rows, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	return rows.RowsAffected() > 0, nil
+}
+
+func (r *RefreshTokenRepo) RevokeSession(ctx context.Context, sessionId string) error {
+	query := `UPDATE refresh_tokens SET revoked_at=now() WHERE session_id=$1 and revoked_at is null`
+	_, err := r.db.Exec(ctx, query, sessionId)
+	return err
+}
+
+func (r *RefreshTokenRepo) SessionActive(ctx context.Context, sessionId string) (bool, error) {
+	var active bool
+	query := `SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE session_id=$1 and revoked_at is null)`
+	err := r.db.QueryRow(ctx, query, sessionId).Scan(&active)
+	return active, err
+}
+
+func (r *RefreshTokenRepo) PurgeExpired(ctx context.Context, before time.Time) (int, error) {
+	rows, err := r.db.Exec(ctx, `DELETE FROM refresh_tokens WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(rows.RowsAffected()), nil
+}
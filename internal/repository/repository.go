@@ -1,16 +1,139 @@
 package repository
 
-import "tasklist/db"
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"tasklist/db"
+)
 
 type Repository struct {
-	UserRepo User
-	TaskRepo Task
-	database *db.Database
+	UserRepo    User
+	TaskRepo    Task
+	ProjectRepo Project
+	TagRepo     Tag
+	EventRepo   TaskEvent
+	TokenRepo   RefreshToken
+	LoginRepo   LoginFailure
+	withinTx    func(ctx context.Context, fn func(repo *Repository) error) error
+}
+
+// WithinTx calls fn with repositories sharing one transaction, which is
+// committed when fn returns nil and rolled back otherwise.
+func (r *Repository) WithinTx(ctx context.Context, fn func(repo *Repository) error) error {
+	return r.withinTx(ctx, fn)
+}
+
+// pgConn is satisfied by both *pgxpool.Pool and pgx.Tx, so the Postgres
+// repositories can run inside a transaction.
+type pgConn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// sqliteConn is satisfied by both *sql.DB and *sql.Tx.
+type sqliteConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqliteTx runs fn in a transaction, committing when it returns nil. Within
+// an enclosing transaction it uses a savepoint instead.
+func sqliteTx(ctx context.Context, conn sqliteConn, fn func(tx *sql.Tx) error) error {
+	if outer, ok := conn.(*sql.Tx); ok {
+		return sqliteSavepoint(ctx, outer, fn)
+	}
+
+	tx, err := conn.(*sql.DB).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func sqliteSavepoint(ctx context.Context, tx *sql.Tx, fn func(tx *sql.Tx) error) error {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT nested`); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.ExecContext(ctx, `ROLLBACK TO nested`)
+		tx.ExecContext(ctx, `RELEASE nested`)
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `RELEASE nested`)
+	return err
 }
 
 func NewRepository(database *db.Database) *Repository {
+	return newPostgresRepository(database.Pool)
+}
+
+func newPostgresRepository(conn pgConn) *Repository {
+	return &Repository{
+		UserRepo:    &UserRepo{db: conn},
+		TaskRepo:    &TaskRepo{db: conn},
+		ProjectRepo: &ProjectRepo{db: conn},
+		TagRepo:     &TagRepo{db: conn},
+		EventRepo:   &TaskEventRepo{db: conn},
+		TokenRepo:   &RefreshTokenRepo{db: conn},
+		LoginRepo:   &LoginFailureRepo{db: conn},
+		withinTx: func(ctx context.Context, fn func(repo *Repository) error) error {
+			return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				return fn(newPostgresRepository(tx))
+			})
+		},
+	}
+}
+
+func NewSQLiteRepository(database *db.SQLite) *Repository {
+	return newSQLiteRepository(database.DB)
+}
+
+func newSQLiteRepository(conn sqliteConn) *Repository {
+	return &Repository{
+		UserRepo:    &SQLiteUserRepo{db: conn},
+		TaskRepo:    &SQLiteTaskRepo{db: conn},
+		ProjectRepo: &SQLiteProjectRepo{db: conn},
+		TagRepo:     &SQLiteTagRepo{db: conn},
+		EventRepo:   &SQLiteTaskEventRepo{db: conn},
+		TokenRepo:   &SQLiteRefreshTokenRepo{db: conn},
+		LoginRepo:   &SQLiteLoginFailureRepo{db: conn},
+		withinTx: func(ctx context.Context, fn func(repo *Repository) error) error {
+			return sqliteTx(ctx, conn, func(tx *sql.Tx) error {
+				return fn(newSQLiteRepository(tx))
+			})
+		},
+	}
+}
+
+func NewMemoryRepository() *Repository {
+	return newMemoryRepository(newMemoryStore())
+}
+
+func newMemoryRepository(store *memoryStore) *Repository {
 	return &Repository{
-		UserRepo: NewUserRepo(database),
-		TaskRepo: NewTaskRepo(database),
+		UserRepo:    NewMemoryUserRepo(store),
+		TaskRepo:    NewMemoryTaskRepo(store),
+		ProjectRepo: NewMemoryProjectRepo(store),
+		TagRepo:     NewMemoryTagRepo(store),
+		EventRepo:   NewMemoryTaskEventRepo(store),
+		TokenRepo:   NewMemoryRefreshTokenRepo(store),
+		LoginRepo:   store.logins,
+		withinTx: func(ctx context.Context, fn func(repo *Repository) error) error {
+			return store.withinTx(func(tx *memoryStore) error {
+				return fn(newMemoryRepository(tx))
+			})
+		},
 	}
 }
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"tasklist/db"
+	"tasklist/internal/models"
+)
+
+// testRepositories returns an empty repository for each backend that runs
+// without a server.
+func testRepositories(t *testing.T) map[string]*Repository {
+	t.Helper()
+	sqlite, err := db.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { sqlite.DB.Close() })
+	migrator, err := db.NewMigrator(sqlite.DB, db.DialectSQLite)
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrating failed: %v", err)
+	}
+	return map[string]*Repository{
+		"memory": NewMemoryRepository(),
+		"sqlite": NewSQLiteRepository(sqlite),
+	}
+}
+
+func createUser(t *testing.T, repo *Repository, username string) int {
+	t.Helper()
+	id, err := repo.UserRepo.Create(context.Background(), &models.User{Username: username, Password: "x"})
+	if err != nil {
+		t.Fatalf("creating user %s failed: %v", username, err)
+	}
+	return id
+}
+
+func createTask(t *testing.T, repo *Repository, userId int, req models.TaskRequest) *models.Task {
+	t.Helper()
+	if req.Priority == "" {
+		req.Priority = models.PriorityNormal
+	}
+	task, err := repo.TaskRepo.Create(context.Background(), userId, req)
+	if err != nil {
+		t.Fatalf("creating task %q failed: %v", req.Title, err)
+	}
+	return task
+}
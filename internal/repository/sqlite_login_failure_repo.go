@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"tasklist/db"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+type SQLiteLoginFailureRepo struct {
+	db sqliteConn
+}
+
+func NewSQLiteLoginFailureRepo(db *db.SQLite) *SQLiteLoginFailureRepo {
+	return &SQLiteLoginFailureRepo{db: db.DB}
+}
+
+func (r *SQLiteLoginFailureRepo) Reserve(ctx context.Context, key string, at, since time.Time, lockout func(failures int) time.Duration) (models.LoginFailures, bool, error) {
+	f := models.LoginFailures{Key: key}
+	var reserved bool
+	err := sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `SELECT failures, last_failure_at, locked_until FROM login_failures WHERE login_key=?`
+		err := tx.QueryRowContext(ctx, query, key).Scan(&f.Failures, &f.LastFailureAt, &f.LockedUntil)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if reserved = reserveLogin(&f, at.UTC(), since, lockout); !reserved {
+			return nil
+		}
+		upsert := `INSERT INTO login_failures (login_key, failures, last_failure_at, locked_until) VALUES (?1, ?2, ?3, ?4)
+			ON CONFLICT (login_key) DO UPDATE SET failures=?2, last_failure_at=?3, locked_until=?4`
+		_, err = tx.ExecContext(ctx, upsert, key, f.Failures, f.LastFailureAt, sqliteTime(f.LockedUntil))
+		return err
+	})
+	return f, reserved, err
+}
+
+func (r *SQLiteLoginFailureRepo) Refund(ctx context.Context, key string, reserved models.LoginFailures) error {
+	query := `UPDATE login_failures SET failures=max(failures - 1, 0),
+			locked_until=CASE WHEN locked_until = ?2 THEN NULL ELSE locked_until END
+		WHERE login_key=?1`
+	_, err := r.db.ExecContext(ctx, query, key, sqliteTime(reserved.LockedUntil))
+	return err
+}
+
+func (r *SQLiteLoginFailureRepo) Reset(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_failures WHERE login_key=?`, key)
+	return err
+}
+
+func (r *SQLiteLoginFailureRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM login_failures WHERE last_failure_at < ?`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
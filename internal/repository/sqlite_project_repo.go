@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"tasklist/db"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+type SQLiteProjectRepo struct {
+	db sqliteConn
+}
+
+func NewSQLiteProjectRepo(db *db.SQLite) *SQLiteProjectRepo {
+	return &SQLiteProjectRepo{db: db.DB}
+}
+
+func (r *SQLiteProjectRepo) Create(ctx context.Context, userId int, name string) (*models.Project, error) {
+	now := time.Now().UTC()
+	query := `INSERT INTO projects (user_id, name, created_at, updated_at) VALUES (?, ?, ?, ?)`
+	res, err := r.db.ExecContext(ctx, query, userId, name, now, now)
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return nil, ErrProjectExists
+		}
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &models.Project{ID: int(id), Name: name, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+func (r *SQLiteProjectRepo) List(ctx context.Context, userId int) ([]models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE user_id=? ORDER BY name`
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+func (r *SQLiteProjectRepo) GetByID(ctx context.Context, projectId, userId int) (*models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE id=? AND user_id=?`
+	project, err := scanProject(r.db.QueryRowContext(ctx, query, projectId, userId))
+	if err != nil {
+		return nil, ErrProjectNotFound
+	}
+	return &project, nil
+}
+
+func (r *SQLiteProjectRepo) Update(ctx context.Context, projectId, userId int, name string) (*models.Project, error) {
+	query := `UPDATE projects SET name=?, updated_at=? WHERE id=? AND user_id=? RETURNING ` + projectColumns
+	project, err := scanProject(r.db.QueryRowContext(ctx, query, name, time.Now().UTC(), projectId, userId))
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return nil, ErrProjectExists
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *SQLiteProjectRepo) Delete(ctx context.Context, projectId, userId int, cascade bool) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `UPDATE tasks SET project_id=NULL, updated_at=?3, version=version+1 WHERE project_id=?1 AND user_id=?2`
+		if cascade {
+			query = `UPDATE tasks SET deleted_at=?3, updated_at=?3, version=version+1
+				WHERE id IN (` + subtreeIDs(db.DialectSQLite, "project_id") + `) AND deleted_at IS NULL`
+		}
+		_, err := tx.ExecContext(ctx, query, projectId, userId, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM projects WHERE id=? AND user_id=?`, projectId, userId)
+		return affectedOrNotFound(res, err, ErrProjectNotFound)
+	})
+}
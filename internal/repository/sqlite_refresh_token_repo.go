@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"tasklist/db"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+type SQLiteRefreshTokenRepo struct {
+	db sqliteConn
+}
+
+func NewSQLiteRefreshTokenRepo(db *db.SQLite) *SQLiteRefreshTokenRepo {
+	return &SQLiteRefreshTokenRepo{db: db.DB}
+}
+
+func (r *SQLiteRefreshTokenRepo) Create(ctx context.Context, token models.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (session_id, user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)`
+	_, err := r.db.ExecContext(ctx, query, token.SessionID, token.UserID, token.TokenHash,
+		token.ExpiresAt.UTC(), time.Now().UTC())
+	return err
+}
+
+func (r *SQLiteRefreshTokenRepo) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	query := `SELECT ` + refreshTokenColumns + ` FROM refresh_tokens WHERE token_hash=?`
+	token, err := scanRefreshToken(r.db.QueryRowContext(ctx, query, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *SQLiteRefreshTokenRepo) MarkUsed(ctx context.Context, id int) (bool, error) {
+	query := `UPDATE refresh_tokens SET used_at=? WHERE id=? AND used_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *SQLiteRefreshTokenRepo) RevokeSession(ctx context.Context, sessionId string) error {
+	query := `UPDATE refresh_tokens SET revoked_at=? WHERE session_id=? AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, time.Now().UTC(), sessionId)
+	return err
+}
+
+func (r *SQLiteRefreshTokenRepo) SessionActive(ctx context.Context, sessionId string) (bool, error) {
+	var active bool
+	query := `SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE session_id=? AND revoked_at IS NULL)`
+	err := r.db.QueryRowContext(ctx, query, sessionId).Scan(&active)
+	return active, err
+}
+
+func (r *SQLiteRefreshTokenRepo) PurgeExpired(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < ?`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tasklist/db"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+type SQLiteTagRepo struct {
+	db sqliteConn
+}
+
+func NewSQLiteTagRepo(db *db.SQLite) *SQLiteTagRepo {
+	return &SQLiteTagRepo{db: db.DB}
+}
+
+func (r *SQLiteTagRepo) Create(ctx context.Context, userId int, name string) (*models.Tag, error) {
+	query := `INSERT INTO tags (user_id, name) VALUES (?, ?)`
+	res, err := r.db.ExecContext(ctx, query, userId, name)
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return nil, ErrTagExists
+		}
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &models.Tag{ID: int(id), Name: name}, nil
+}
+
+func (r *SQLiteTagRepo) List(ctx context.Context, userId int) ([]models.Tag, error) {
+	query := `SELECT id, name FROM tags WHERE user_id=? ORDER BY name`
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (r *SQLiteTagRepo) GetByID(ctx context.Context, tagId, userId int) (*models.Tag, error) {
+	var tag models.Tag
+	query := `SELECT id, name FROM tags WHERE id=? AND user_id=?`
+	if err := r.db.QueryRowContext(ctx, query, tagId, userId).Scan(&tag.ID, &tag.Name); err != nil {
+		return nil, ErrTagNotFound
+	}
+	return &tag, nil
+}
+
+func (r *SQLiteTagRepo) Update(ctx context.Context, tagId, userId int, name string) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `UPDATE tags SET name=? WHERE id=? AND user_id=?`
+		res, err := tx.ExecContext(ctx, query, name, tagId, userId)
+		if err != nil && isSQLiteUniqueViolation(err) {
+			return ErrTagExists
+		}
+		if err := affectedOrNotFound(res, err, ErrTagNotFound); err != nil {
+			return err
+		}
+		return touchSQLiteTaggedTasks(ctx, tx, tagId, userId)
+	})
+}
+
+func (r *SQLiteTagRepo) Delete(ctx context.Context, tagId, userId int) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		// The tasks are touched first, while the tag is still on them.
+		if err := touchSQLiteTaggedTasks(ctx, tx, tagId, userId); err != nil {
+			return err
+		}
+		query := `DELETE FROM tags WHERE id=? AND user_id=?`
+		res, err := tx.ExecContext(ctx, query, tagId, userId)
+		return affectedOrNotFound(res, err, ErrTagNotFound)
+	})
+}
+
+func (r *SQLiteTagRepo) Attach(ctx context.Context, taskId, userId int, name string) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := touchSQLiteTask(ctx, tx, taskId, userId); err != nil {
+			return err
+		}
+		upsert := `INSERT INTO tags (user_id, name) VALUES (?, ?) ON CONFLICT (user_id, name) DO NOTHING`
+		if _, err := tx.ExecContext(ctx, upsert, userId, name); err != nil {
+			return err
+		}
+		link := `INSERT OR IGNORE INTO task_tags (task_id, tag_id)
+			SELECT ?, id FROM tags WHERE user_id=? AND name=?`
+		_, err := tx.ExecContext(ctx, link, taskId, userId, name)
+		return err
+	})
+}
+
+func (r *SQLiteTagRepo) Detach(ctx context.Context, taskId, userId int, name string) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := touchSQLiteTask(ctx, tx, taskId, userId); err != nil {
+			return err
+		}
+		query := `DELETE FROM task_tags
+			WHERE task_id=? AND tag_id = (SELECT id FROM tags WHERE user_id=? AND name=?)`
+		res, err := tx.ExecContext(ctx, query, taskId, userId, name)
+		return affectedOrNotFound(res, err, ErrTagNotFound)
+	})
+}
+
+// touchSQLiteTask bumps the update time of a task, failing with
+// ErrTaskNotFound unless userId owns it.
+func touchSQLiteTask(ctx context.Context, tx *sql.Tx, taskId, userId int) error {
+	query := `UPDATE tasks SET updated_at=?, version=version+1 WHERE id=? AND user_id=? AND deleted_at IS NULL`
+	res, err := tx.ExecContext(ctx, query, time.Now().UTC(), taskId, userId)
+	return affectedOrNotFound(res, err, ErrTaskNotFound)
+}
+
+// touchSQLiteTaggedTasks counts renaming or deleting a tag as a change to the
+// live tasks of userId that have it.
+func touchSQLiteTaggedTasks(ctx context.Context, tx *sql.Tx, tagId, userId int) error {
+	query := `UPDATE tasks SET updated_at=?, version=version+1
+		WHERE user_id=? AND deleted_at IS NULL AND id IN (SELECT task_id FROM task_tags WHERE tag_id=?)`
+	_, err := tx.ExecContext(ctx, query, time.Now().UTC(), userId, tagId)
+	return err
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"tasklist/db"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+type SQLiteTaskEventRepo struct {
+	db sqliteConn
+}
+
+func NewSQLiteTaskEventRepo(db *db.SQLite) *SQLiteTaskEventRepo {
+	return &SQLiteTaskEventRepo{db: db.DB}
+}
+
+func (r *SQLiteTaskEventRepo) Record(ctx context.Context, events ...models.TaskEvent) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		now := time.Now().UTC()
+		query := `INSERT INTO task_events (task_id, actor_id, action, changes, created_at) VALUES (?, ?, ?, ?, ?)`
+		for _, event := range events {
+			changes, err := json.Marshal(event.Changes)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, query, event.TaskID, event.ActorID, event.Action, string(changes), now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteTaskEventRepo) History(ctx context.Context, taskId, userId int) ([]models.TaskEvent, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM tasks WHERE id=? AND user_id=?)`
+	if err := r.db.QueryRowContext(ctx, query, taskId, userId).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+
+	query = `SELECT ` + taskEventColumns + `
+		FROM task_events e LEFT JOIN users u ON u.id = e.actor_id
+		WHERE e.task_id=?
+		ORDER BY e.id`
+	rows, err := r.db.QueryContext(ctx, query, taskId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.TaskEvent{}
+	for rows.Next() {
+		event, err := scanTaskEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
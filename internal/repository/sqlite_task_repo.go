@@ -0,0 +1,485 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"tasklist/db"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"tasklist/internal/models"
+)
+
+type SQLiteTaskRepo struct {
+	db sqliteConn
+}
+
+func NewSQLiteTaskRepo(db *db.SQLite) *SQLiteTaskRepo {
+	return &SQLiteTaskRepo{db: db.DB}
+}
+
+func (r *SQLiteTaskRepo) Create(ctx context.Context, userId int, task models.TaskRequest) (*models.Task, error) {
+	now := time.Now().UTC()
+	query := `INSERT INTO tasks (user_id, title, description, priority, deadline, project_id, parent_id,
+		recurrence, created_at, updated_at, status_changed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING ` + taskColumns
+	created, err := scanTask(r.db.QueryRowContext(ctx, query, userId, task.Title, task.Description,
+		task.Priority, sqliteTime(task.Deadline), task.ProjectID, task.ParentID, task.Recurrence, now, now, now))
+	if err != nil {
+		return nil, err
+	}
+	return newTask(created), nil
+}
+
+func (r *SQLiteTaskRepo) SetStatus(ctx context.Context, taskId, userId int, from, to models.TaskStatus, cascade bool) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := checkSQLiteTaskStatus(ctx, tx, taskId, userId, from); err != nil {
+			return err
+		}
+		set := `UPDATE tasks SET status=?3, status_changed_at=?4,
+			completed_at=CASE WHEN ?3='done' THEN ?4 END, updated_at=?4, version=version+1 `
+		query := set + `WHERE id=?1 AND user_id=?2`
+		if cascade {
+			query = set + `WHERE id IN (` + subtreeIDs(db.DialectSQLite, "id") + `) AND deleted_at IS NULL
+				AND (id=?1 OR status NOT IN ` + closedStatuses + `)`
+		}
+		_, err := tx.ExecContext(ctx, query, taskId, userId, string(to), time.Now().UTC())
+		return err
+	})
+}
+
+func (r *SQLiteTaskRepo) SetNextOccurrence(ctx context.Context, taskId, userId, nextId int) error {
+	query := `UPDATE tasks SET next_occurrence_id=? WHERE id=? AND user_id=?`
+	res, err := r.db.ExecContext(ctx, query, nextId, taskId, userId)
+	return affectedOrNotFound(res, err, ErrTaskNotFound)
+}
+
+func (r *SQLiteTaskRepo) List(ctx context.Context, userId int, params models.TaskListParams) (*models.TaskList, error) {
+	list, count, err := taskListQueries(db.DialectSQLite, userId, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, count.String(), count.args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	tasks, err := r.queryTasks(ctx, list.String(), list.args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadDetails(ctx, tasks); err != nil {
+		return nil, err
+	}
+	return newTaskList(tasks, total, params), nil
+}
+
+// Search matches every word of query as a case-insensitive substring of the
+// title or description.
+func (r *SQLiteTaskRepo) Search(ctx context.Context, userId int, query string, limit int) ([]models.Task, error) {
+	q := &sqlQuery{dialect: db.DialectSQLite}
+	conds := []string{"user_id = " + q.arg(userId), "deleted_at IS NULL"}
+	for _, term := range searchTerms(query) {
+		conds = append(conds, "(title || ' ' || description) LIKE "+q.arg(likePattern(term))+" ESCAPE '\\'")
+	}
+	fmt.Fprintf(&q.text, `SELECT %s FROM tasks WHERE %s ORDER BY id DESC LIMIT %s`,
+		taskColumns, strings.Join(conds, " AND "), q.arg(limit))
+	tasks, err := r.queryTasks(ctx, q.String(), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	return tasks, r.loadDetails(ctx, tasks)
+}
+
+func (r *SQLiteTaskRepo) ListAll(ctx context.Context) ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks
+		WHERE is_overdue=FALSE AND status NOT IN ` + closedStatuses + ` AND deleted_at IS NULL`
+	return r.queryTasks(ctx, query)
+}
+
+func (r *SQLiteTaskRepo) GetByID(ctx context.Context, taskId, userId int) (*models.Task, error) {
+	query := `SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE id=? AND user_id=? AND deleted_at IS NULL`
+	task, err := scanTask(r.db.QueryRowContext(ctx, query, taskId, userId))
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+	tasks := []models.Task{task}
+	if err := r.loadDetails(ctx, tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+func (r *SQLiteTaskRepo) Descendants(ctx context.Context, taskId, userId int) ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks
+		WHERE id IN (` + subtreeIDs(db.DialectSQLite, "id") + `) AND id <> ?1 AND deleted_at IS NULL
+		ORDER BY id`
+	return r.queryTasks(ctx, query, taskId, userId)
+}
+
+func (r *SQLiteTaskRepo) Update(ctx context.Context, taskId, userId, version int, task models.TaskRequest) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := checkSQLiteTaskVersion(ctx, tx, taskId, userId, version); err != nil {
+			return err
+		}
+		query := `UPDATE tasks SET title=?, description=?, priority=?, deadline=?, project_id=?, parent_id=?,
+			recurrence=?, updated_at=?, version=version+1
+			WHERE id=? AND user_id=?`
+		_, err := tx.ExecContext(ctx, query, task.Title, task.Description, task.Priority,
+			sqliteTime(task.Deadline), task.ProjectID, task.ParentID, task.Recurrence, time.Now().UTC(), taskId, userId)
+		return err
+	})
+}
+
+func (r *SQLiteTaskRepo) Delete(ctx context.Context, taskId, userId, version int) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := checkSQLiteTaskVersion(ctx, tx, taskId, userId, version); err != nil {
+			return err
+		}
+		query := `UPDATE tasks SET deleted_at=?3, updated_at=?3, version=version+1
+			WHERE id IN (` + subtreeIDs(db.DialectSQLite, "id") + `) AND deleted_at IS NULL`
+		_, err := tx.ExecContext(ctx, query, taskId, userId, time.Now().UTC())
+		return err
+	})
+}
+
+// checkSQLiteTaskVersion fails with ErrTaskNotFound unless userId owns a live
+// task, and with ErrVersionMismatch unless it is at version or version is 0.
+func checkSQLiteTaskVersion(ctx context.Context, tx *sql.Tx, taskId, userId, version int) error {
+	var current int
+	query := `SELECT version FROM tasks WHERE id=? AND user_id=? AND deleted_at IS NULL`
+	if err := tx.QueryRowContext(ctx, query, taskId, userId).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
+		}
+		return err
+	}
+	if version != 0 && version != current {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// checkSQLiteTaskStatus fails with ErrTaskNotFound unless userId owns a live
+// task, and with ErrStatusChanged unless it is at status.
+func checkSQLiteTaskStatus(ctx context.Context, tx *sql.Tx, taskId, userId int, status models.TaskStatus) error {
+	var current models.TaskStatus
+	query := `SELECT status FROM tasks WHERE id=? AND user_id=? AND deleted_at IS NULL`
+	if err := tx.QueryRowContext(ctx, query, taskId, userId).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
+		}
+		return err
+	}
+	if current != status {
+		return ErrStatusChanged
+	}
+	return nil
+}
+
+func (r *SQLiteTaskRepo) Restore(ctx context.Context, taskId, userId int) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		var deletedAt time.Time
+		var parentInTrash bool
+		query := `SELECT t.deleted_at, p.deleted_at IS NOT NULL
+			FROM tasks t LEFT JOIN tasks p ON p.id = t.parent_id
+			WHERE t.id=? AND t.user_id=? AND t.deleted_at IS NOT NULL`
+		if err := tx.QueryRowContext(ctx, query, taskId, userId).Scan(&deletedAt, &parentInTrash); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrTaskNotFound
+			}
+			return err
+		}
+		if parentInTrash {
+			return ErrParentInTrash
+		}
+		restore := `UPDATE tasks SET deleted_at=NULL, updated_at=?4, version=version+1
+			WHERE id IN (` + subtreeIDs(db.DialectSQLite, "id") + `) AND deleted_at=?3`
+		_, err := tx.ExecContext(ctx, restore, taskId, userId, deletedAt, time.Now().UTC())
+		return err
+	})
+}
+
+func (r *SQLiteTaskRepo) Purge(ctx context.Context, taskId, userId int) error {
+	query := `DELETE FROM tasks WHERE id=? AND user_id=? AND deleted_at IS NOT NULL`
+	res, err := r.db.ExecContext(ctx, query, taskId, userId)
+	return affectedOrNotFound(res, err, ErrTaskNotFound)
+}
+
+func (r *SQLiteTaskRepo) EmptyTrash(ctx context.Context, userId int) (int, error) {
+	return r.purgeTrash(ctx, `user_id=?`, userId)
+}
+
+func (r *SQLiteTaskRepo) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	return r.purgeTrash(ctx, `deleted_at < ?`, before.UTC())
+}
+
+// purgeTrash permanently deletes the trashed tasks matching cond. The tasks
+// are counted first because subtasks removed by the parent_id cascade are not
+// reported as affected rows.
+func (r *SQLiteTaskRepo) purgeTrash(ctx context.Context, cond string, arg any) (int, error) {
+	var purged int
+	err := sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		where := ` FROM tasks WHERE deleted_at IS NOT NULL AND ` + cond
+		if err := tx.QueryRowContext(ctx, `SELECT count(*)`+where, arg).Scan(&purged); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE`+where, arg)
+		return err
+	})
+	return purged, err
+}
+
+// Changes reads the change counter of the user first, and its horizon last
+// so that it covers the tombstones dropped meanwhile.
+func (r *SQLiteTaskRepo) Changes(ctx context.Context, userId int, since int64, limit int) (*models.TaskChangeLog, error) {
+	log := &models.TaskChangeLog{}
+	if err := r.db.QueryRowContext(ctx, `SELECT change_seq FROM users WHERE id=?`, userId).Scan(&log.Latest); err != nil {
+		return nil, err
+	}
+	tasks, tombstones := taskChangeQueries(db.DialectSQLite, userId, since, log.Latest, limit)
+	changed, err := r.queryChangedTasks(ctx, tasks)
+	if err != nil {
+		return nil, err
+	}
+	purged, err := r.queryTombstones(ctx, tombstones)
+	if err != nil {
+		return nil, err
+	}
+	log.Changes = sortTaskChanges(append(changed, purged...), limit)
+	err = r.db.QueryRowContext(ctx, `SELECT sync_horizon FROM users WHERE id=?`, userId).Scan(&log.Horizon)
+	return log, err
+}
+
+func (r *SQLiteTaskRepo) queryChangedTasks(ctx context.Context, q *sqlQuery) ([]models.TaskChange, error) {
+	rows, err := r.db.QueryContext(ctx, q.String(), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	var seqs []int64
+	for rows.Next() {
+		var seq int64
+		task, err := scanTask(rows, &seq)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+		seqs = append(seqs, seq)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if err := r.loadDetails(ctx, tasks); err != nil {
+		return nil, err
+	}
+	changes := make([]models.TaskChange, len(tasks))
+	for i := range tasks {
+		changes[i] = models.TaskChange{Seq: seqs[i], Task: &tasks[i]}
+	}
+	return changes, nil
+}
+
+func (r *SQLiteTaskRepo) queryTombstones(ctx context.Context, q *sqlQuery) ([]models.TaskChange, error) {
+	rows, err := r.db.QueryContext(ctx, q.String(), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.TaskChange
+	for rows.Next() {
+		change := models.TaskChange{Purged: &models.TaskTombstone{}}
+		if err := rows.Scan(&change.Purged.ID, &change.Purged.DeletedAt, &change.Seq); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// PurgeTombstones raises the horizon of each user to the last change it
+// drops, so that clients that have not synced it are told to start over.
+func (r *SQLiteTaskRepo) PurgeTombstones(ctx context.Context, before time.Time) (int, error) {
+	var purged int64
+	err := sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		horizon := `UPDATE users SET sync_horizon = max(sync_horizon,
+				(SELECT max(change_seq) FROM task_tombstones t WHERE t.user_id = users.id AND t.deleted_at < ?1))
+			WHERE id IN (SELECT user_id FROM task_tombstones WHERE deleted_at < ?1)`
+		if _, err := tx.ExecContext(ctx, horizon, before.UTC()); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM task_tombstones WHERE deleted_at < ?`, before.UTC())
+		if err != nil {
+			return err
+		}
+		purged, err = res.RowsAffected()
+		return err
+	})
+	return int(purged), err
+}
+
+func (r *SQLiteTaskRepo) AddDependency(ctx context.Context, taskId, blockerId, userId int) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := touchSQLiteTask(ctx, tx, taskId, userId); err != nil {
+			return err
+		}
+		query := `INSERT OR IGNORE INTO task_dependencies (task_id, blocked_by_id)
+			SELECT ?, id FROM tasks WHERE id=? AND user_id=? AND deleted_at IS NULL`
+		_, err := tx.ExecContext(ctx, query, taskId, blockerId, userId)
+		return err
+	})
+}
+
+func (r *SQLiteTaskRepo) RemoveDependency(ctx context.Context, taskId, blockerId, userId int) error {
+	return sqliteTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := touchSQLiteTask(ctx, tx, taskId, userId); err != nil {
+			return err
+		}
+		query := `DELETE FROM task_dependencies WHERE task_id=? AND blocked_by_id=?`
+		res, err := tx.ExecContext(ctx, query, taskId, blockerId)
+		return affectedOrNotFound(res, err, ErrDependencyNotFound)
+	})
+}
+
+func (r *SQLiteTaskRepo) Blockers(ctx context.Context, taskId, userId int) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx, blockerIDs(db.DialectSQLite), taskId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *SQLiteTaskRepo) MarkOverdued(ctx context.Context, taskId int) (bool, error) {
+	query := `UPDATE tasks SET is_overdue=TRUE, updated_at=?, version=version+1
+		WHERE id=? AND is_overdue=FALSE AND status NOT IN ` + closedStatuses + ` AND deleted_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), taskId)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *SQLiteTaskRepo) queryTasks(ctx context.Context, query string, args ...any) ([]models.Task, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// loadDetails fills in the tags and dependencies of tasks.
+func (r *SQLiteTaskRepo) loadDetails(ctx context.Context, tasks []models.Task) error {
+	if err := r.loadTags(ctx, tasks); err != nil {
+		return err
+	}
+	return r.loadDependencies(ctx, tasks)
+}
+
+func (r *SQLiteTaskRepo) loadTags(ctx context.Context, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	q := taskTagsQuery(db.DialectSQLite, tasks)
+	rows, err := r.db.QueryContext(ctx, q.String(), q.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byTask := make(map[int][]string)
+	for rows.Next() {
+		var taskId int
+		var name string
+		if err := rows.Scan(&taskId, &name); err != nil {
+			return err
+		}
+		byTask[taskId] = append(byTask[taskId], name)
+	}
+	setTaskTags(tasks, byTask)
+	return rows.Err()
+}
+
+func (r *SQLiteTaskRepo) loadDependencies(ctx context.Context, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	q := taskDependenciesQuery(db.DialectSQLite, tasks)
+	rows, err := r.db.QueryContext(ctx, q.String(), q.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var deps []taskDependency
+	for rows.Next() {
+		var dep taskDependency
+		if err := rows.Scan(&dep.taskId, &dep.blockedById, &dep.status); err != nil {
+			return err
+		}
+		deps = append(deps, dep)
+	}
+	setTaskDependencies(tasks, deps)
+	return rows.Err()
+}
+
+// affectedOrNotFound passes err through, or returns notFound when the
+// statement matched no rows.
+func affectedOrNotFound(res sql.Result, err error, notFound error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// sqliteTime stores timestamps in UTC so that they compare correctly as text.
+func sqliteTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
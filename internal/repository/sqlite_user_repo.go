@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"tasklist/db"
+	"tasklist/internal/models"
+)
+
+type SQLiteUserRepo struct {
+	db sqliteConn
+}
+
+func NewSQLiteUserRepo(db *db.SQLite) *SQLiteUserRepo {
+	return &SQLiteUserRepo{db: db.DB}
+}
+
+func (r *SQLiteUserRepo) Create(ctx context.Context, user *models.User) (int, error) {
+	query := `INSERT INTO users (username, password) VALUES (?, ?)`
+	res, err := r.db.ExecContext(ctx, query, user.Username, user.Password)
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return 0, ErrUserExists
+		}
+		return 0, err
+	}
+	userId, err := res.LastInsertId()
+	return int(userId), err
+}
+
+func (r *SQLiteUserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+
+	query := `SELECT id, username, password FROM users WHERE username=?`
+
+	row := r.db.QueryRowContext(ctx, query, username)
+	if err := row.Scan(&user.ID, &user.Username, &user.Password); err != nil {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *SQLiteUserRepo) UpdatePassword(ctx context.Context, userId int, password string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET password=? WHERE id=?`, password, userId)
+	return affectedOrNotFound(res, err, ErrUserNotFound)
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"tasklist/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"tasklist/internal/models"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+)
+
+type Tag interface {
+	Create(ctx context.Context, userId int, name string) (*models.Tag, error)
+	List(ctx context.Context, userId int) ([]models.Tag, error)
+	GetByID(ctx context.Context, tagId, userId int) (*models.Tag, error)
+	Update(ctx context.Context, tagId, userId int, name string) error
+	Delete(ctx context.Context, tagId, userId int) error
+
+	// Attach tags the task with name, creating the tag when the user has none
+	// by that name yet.
+	Attach(ctx context.Context, taskId, userId int, name string) error
+	Detach(ctx context.Context, taskId, userId int, name string) error
+}
+
+type TagRepo struct {
+	db pgConn
+}
+
+func NewTagRepo(db *db.Database) *TagRepo {
+	return &TagRepo{db: db.Pool}
+}
+
+func (r *TagRepo) Create(ctx context.Context, userId int, name string) (*models.Tag, error) {
+	tag := models.Tag{Name: name}
+	query := `INSERT INTO tags (user_id, name) VALUES ($1, $2) RETURNING id`
+	if err := r.db.QueryRow(ctx, query, userId, name).Scan(&tag.ID); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrTagExists
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *TagRepo) List(ctx context.Context, userId int) ([]models.Tag, error) {
+	query := `SELECT id, name FROM tags WHERE user_id=$1 ORDER BY name`
+	rows, err := r.db.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (r *TagRepo) GetByID(ctx context.Context, tagId, userId int) (*models.Tag, error) {
+	var tag models.Tag
+	query := `SELECT id, name FROM tags WHERE id=$1 and user_id=$2`
+	if err := r.db.QueryRow(ctx, query, tagId, userId).Scan(&tag.ID, &tag.Name); err != nil {
+		return nil, ErrTagNotFound
+	}
+	return &tag, nil
+}
+
+func (r *TagRepo) Update(ctx context.Context, tagId, userId int, name string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		query := `UPDATE tags SET name=$1 WHERE id=$2 and user_id=$3`
+		rows, err := tx.Exec(ctx, query, name, tagId, userId)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrTagExists
+			}
+			return err
+		}
+		if rows.RowsAffected() == 0 {
+			return ErrTagNotFound
+		}
+		return touchTaggedTasks(ctx, tx, tagId, userId)
+	})
+}
+
+func (r *TagRepo) Delete(ctx context.Context, tagId, userId int) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// The tasks are touched first, while the tag is still on them.
+		if err := touchTaggedTasks(ctx, tx, tagId, userId); err != nil {
+			return err
+		}
+		query := `DELETE FROM tags WHERE id=$1 and user_id=$2`
+		rows, err := tx.Exec(ctx, query, tagId, userId)
+		if err != nil {
+			return err
+		}
+		if rows.RowsAffected() == 0 {
+			return ErrTagNotFound
+		}
+		return nil
+	})
+}
+
+func (r *TagRepo) Attach(ctx context.Context, taskId, userId int, name string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := touchTask(ctx, tx, taskId, userId); err != nil {
+			return err
+		}
+		var tagId int
+		upsert := `INSERT INTO tags (user_id, name) VALUES ($1, $2)
+			ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id`
+		if err := tx.QueryRow(ctx, upsert, userId, name).Scan(&tagId); err != nil {
+			return err
+		}
+		link := `INSERT INTO task_tags (task_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		_, err := tx.Exec(ctx, link, taskId, tagId)
+		return err
+	})
+}
+
+func (r *TagRepo) Detach(ctx context.Context, taskId, userId int, name string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := touchTask(ctx, tx, taskId, userId); err != nil {
+			return err
+		}
+		query := `DELETE FROM task_tags
+			WHERE task_id=$1 AND tag_id = (SELECT id FROM tags WHERE user_id=$2 AND name=$3)`
+		rows, err := tx.Exec(ctx, query, taskId, userId, name)
+		if err != nil {
+			return err
+		}
+		if rows.RowsAffected() == 0 {
+			return ErrTagNotFound
+		}
+		return nil
+	})
+}
+
+// touchTask bumps the update time of a task, failing with ErrTaskNotFound
+// unless userId owns it.
+func touchTask(ctx context.Context, tx pgx.Tx, taskId, userId int) error {
+	query := `UPDATE tasks SET updated_at=now(), version=version+1 WHERE id=$1 and user_id=$2 and deleted_at is null`
+	rows, err := tx.Exec(ctx, query, taskId, userId)
+	if err != nil {
+		return err
+	}
+	if rows.RowsAffected() == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// touchTaggedTasks counts renaming or deleting a tag as a change to the live
+// tasks of userId that have it.
+func touchTaggedTasks(ctx context.Context, tx pgx.Tx, tagId, userId int) error {
+	query := `UPDATE tasks SET updated_at=now(), version=version+1
+		WHERE user_id=$2 and deleted_at is null and id IN (SELECT task_id FROM task_tags WHERE tag_id=$1)`
+	_, err := tx.Exec(ctx, query, tagId, userId)
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"tasklist/db"
+	"tasklist/internal/models"
+)
+
+// taskChangeQueries builds the queries for the tasks and tombstones of a user
+// whose last change is numbered after since and up to latest, oldest first.
+// Each fetches one more than limit, as either may fill the page alone.
+//
+// Changes numbered up to latest, the counter of the user when the page is
+// read, are all committed, so later statements see each of them or a change
+// that replaced it, which is numbered after latest and left for the next
+// page.
+func taskChangeQueries(dialect db.Dialect, userId int, since, latest int64, limit int) (tasks, tombstones *sqlQuery) {
+	tasks = &sqlQuery{dialect: dialect}
+	tombstones = &sqlQuery{dialect: dialect}
+	for _, q := range []*sqlQuery{tasks, tombstones} {
+		columns, table := taskColumns+", change_seq", "tasks"
+		if q == tombstones {
+			columns, table = "task_id, deleted_at, change_seq", "task_tombstones"
+		}
+		fmt.Fprintf(&q.text, `SELECT %s FROM %s WHERE user_id = %s AND change_seq > %s AND change_seq <= %s
+			ORDER BY change_seq LIMIT %s`, columns, table, q.arg(userId), q.arg(since), q.arg(latest), q.arg(limit+1))
+	}
+	return tasks, tombstones
+}
+
+// sortTaskChanges orders the changes read by the backends and keeps the first
+// limit+1.
+func sortTaskChanges(changes []models.TaskChange, limit int) []models.TaskChange {
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Seq < changes[j].Seq })
+	return changes[:min(len(changes), limit+1)]
+}
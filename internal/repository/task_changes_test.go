@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+// changeIDs lists the changes of a log as task IDs, negated for purged tasks.
+func changeIDs(log *models.TaskChangeLog) []int {
+	ids := []int{}
+	for _, change := range log.Changes {
+		if change.Purged != nil {
+			ids = append(ids, -change.Purged.ID)
+		} else {
+			ids = append(ids, change.Task.ID)
+		}
+	}
+	return ids
+}
+
+func TestTaskChanges(t *testing.T) {
+	ctx := context.Background()
+	for name, repo := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			tasks := repo.TaskRepo
+			userId := createUser(t, repo, "alice")
+			otherId := createUser(t, repo, "bob")
+			a := createTask(t, repo, userId, models.TaskRequest{Title: "a"})
+			b := createTask(t, repo, userId, models.TaskRequest{Title: "b"})
+			c := createTask(t, repo, userId, models.TaskRequest{Title: "c"})
+			createTask(t, repo, otherId, models.TaskRequest{Title: "not alice's"})
+
+			log, err := tasks.Changes(ctx, userId, 0, 10)
+			if err != nil {
+				t.Fatalf("Changes failed: %v", err)
+			}
+			if got := changeIDs(log); !slices.Equal(got, []int{a.ID, b.ID, c.ID}) || log.Latest != 3 {
+				t.Fatalf("Changes = %v up to %d, want [%d %d %d] up to 3", got, log.Latest, a.ID, b.ID, c.ID)
+			}
+			for i, change := range log.Changes {
+				if change.Seq != int64(i+1) {
+					t.Errorf("change %d has seq %d", i, change.Seq)
+				}
+			}
+
+			// An update moves a task after the others; the trash and purges
+			// are changes too.
+			if err := tasks.Update(ctx, a.ID, userId, 0, models.TaskRequest{Title: "a2", Priority: models.PriorityHigh}); err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+			if err := tasks.Delete(ctx, b.ID, userId, 0); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if err := tasks.Delete(ctx, c.ID, userId, 0); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if err := tasks.Purge(ctx, c.ID, userId); err != nil {
+				t.Fatalf("Purge failed: %v", err)
+			}
+
+			log, err = tasks.Changes(ctx, userId, 3, 10)
+			if err != nil {
+				t.Fatalf("Changes failed: %v", err)
+			}
+			if got := changeIDs(log); !slices.Equal(got, []int{a.ID, b.ID, -c.ID}) {
+				t.Fatalf("Changes since 3 = %v, want [%d %d %d]", got, a.ID, b.ID, -c.ID)
+			}
+			if log.Changes[1].Task.DeletedAt == nil {
+				t.Errorf("trashed task has no DeletedAt")
+			}
+			if last := log.Changes[2].Seq; last != log.Latest {
+				t.Errorf("last change has seq %d, Latest is %d", last, log.Latest)
+			}
+
+			page, err := tasks.Changes(ctx, userId, 0, 1)
+			if err != nil {
+				t.Fatalf("Changes failed: %v", err)
+			}
+			if got := changeIDs(page); !slices.Equal(got, []int{a.ID, b.ID}) {
+				t.Errorf("Changes with limit 1 = %v, want [%d %d]", got, a.ID, b.ID)
+			}
+
+			if log.Horizon != 0 {
+				t.Fatalf("Horizon before purging tombstones = %d", log.Horizon)
+			}
+			purged, err := tasks.PurgeTombstones(ctx, time.Now().Add(time.Minute))
+			if err != nil {
+				t.Fatalf("PurgeTombstones failed: %v", err)
+			}
+			if purged != 1 {
+				t.Errorf("PurgeTombstones dropped %d tombstones, want 1", purged)
+			}
+			after, err := tasks.Changes(ctx, userId, 0, 10)
+			if err != nil {
+				t.Fatalf("Changes failed: %v", err)
+			}
+			if after.Horizon != log.Changes[2].Seq {
+				t.Errorf("Horizon = %d, want the purged change %d", after.Horizon, log.Changes[2].Seq)
+			}
+			if got := changeIDs(after); !slices.Equal(got, []int{a.ID, b.ID}) {
+				t.Errorf("Changes after PurgeTombstones = %v, want [%d %d]", got, a.ID, b.ID)
+			}
+
+			other, err := tasks.Changes(ctx, otherId, 0, 10)
+			if err != nil {
+				t.Fatalf("Changes failed: %v", err)
+			}
+			if len(other.Changes) != 1 || other.Latest != 1 || other.Horizon != 0 {
+				t.Errorf("other user's changes = %v up to %d, horizon %d", changeIDs(other), other.Latest, other.Horizon)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"tasklist/db"
+
+	"github.com/jackc/pgx/v5"
+
+	"tasklist/internal/models"
+)
+
+type TaskEvent interface {
+	// Record appends events to the histories of their tasks.
+	Record(ctx context.Context, events ...models.TaskEvent) error
+	// History lists the events of a task, oldest first. Tasks in the trash
+	// keep their history until purged.
+	History(ctx context.Context, taskId, userId int) ([]models.TaskEvent, error)
+}
+
+type TaskEventRepo struct {
+	db pgConn
+}
+
+func NewTaskEventRepo(db *db.Database) *TaskEventRepo {
+	return &TaskEventRepo{db: db.Pool}
+}
+
+func (r *TaskEventRepo) Record(ctx context.Context, events ...models.TaskEvent) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		query := `INSERT INTO task_events (task_id, actor_id, action, changes) VALUES ($1, $2, $3, $4)`
+		for _, event := range events {
+			changes, err := json.Marshal(event.Changes)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, query, event.TaskID, event.ActorID, event.Action, string(changes)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *TaskEventRepo) History(ctx context.Context, taskId, userId int) ([]models.TaskEvent, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM tasks WHERE id=$1 and user_id=$2)`
+	if err := r.db.QueryRow(ctx, query, taskId, userId).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+
+	query = `SELECT ` + taskEventColumns + `
+		FROM task_events e LEFT JOIN users u ON u.id = e.actor_id
+		WHERE e.task_id=$1
+		ORDER BY e.id`
+	rows, err := r.db.Query(ctx, query, taskId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.TaskEvent{}
+	for rows.Next() {
+		event, err := scanTaskEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// taskEventColumns selects the columns read by scanTaskEvent from task_events
+// e joined with users u.
+const taskEventColumns = `e.id, e.task_id, e.action, e.actor_id, COALESCE(u.username, ''), e.changes, e.created_at`
+
+func scanTaskEvent(row rowScanner) (models.TaskEvent, error) {
+	var event models.TaskEvent
+	var changes []byte
+	if err := row.Scan(&event.ID, &event.TaskID, &event.Action, &event.ActorID, &event.Actor,
+		&changes, &event.CreatedAt); err != nil {
+		return event, err
+	}
+	if event.ActorID == nil {
+		event.Actor = models.SystemActor
+	}
+	return event, json.Unmarshal(changes, &event.Changes)
+}
@@ -0,0 +1,383 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tasklist/db"
+	"tasklist/internal/models"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// deadlineSentinel sorts tasks without a deadline after every dated task.
+var deadlineSentinel = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+// cursorTimeFormat is fixed-width so that encoded keys compare as text.
+const cursorTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// listCursor identifies the last task of a page by its sort key and ID.
+type listCursor struct {
+	Key string `json:"k,omitempty"`
+	ID  int    `json:"id"`
+}
+
+func encodeCursor(c listCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (listCursor, error) {
+	var c listCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// sortKey returns the cursor key of task for a sort field. Fields ordered by
+// ID alone have an empty key.
+func sortKey(task models.Task, field string) string {
+	switch field {
+	case models.TaskSortDeadline:
+		deadline := deadlineSentinel
+		if task.Deadline != nil {
+			deadline = *task.Deadline
+		}
+		return deadline.UTC().Format(cursorTimeFormat)
+	case models.TaskSortTitle:
+		return task.Title
+	case models.TaskSortPriority:
+		return strconv.Itoa(task.Priority.Rank())
+	case models.TaskSortCreated:
+		return task.CreatedAt.UTC().Format(cursorTimeFormat)
+	case models.TaskSortUpdated:
+		return task.UpdatedAt.UTC().Format(cursorTimeFormat)
+	}
+	return ""
+}
+
+// newTaskList trims the one-past-the-page task fetched by the backends and
+// derives the next cursor from the last task kept.
+func newTaskList(tasks []models.Task, total int, params models.TaskListParams) *models.TaskList {
+	list := &models.TaskList{Tasks: tasks, Total: total}
+	if len(tasks) > params.Limit {
+		list.Tasks = tasks[:params.Limit]
+		field, _ := params.SortField()
+		last := list.Tasks[len(list.Tasks)-1]
+		list.NextCursor = encodeCursor(listCursor{Key: sortKey(last, field), ID: last.ID})
+	}
+	return list
+}
+
+// sqlQuery builds a parameterised statement with numbered placeholders, so the
+// same argument may be referenced more than once.
+type sqlQuery struct {
+	dialect db.Dialect
+	text    strings.Builder
+	args    []any
+}
+
+func (q *sqlQuery) arg(v any) string {
+	if t, ok := v.(time.Time); ok && q.dialect == db.DialectSQLite {
+		v = t.UTC()
+	}
+	q.args = append(q.args, v)
+	n := strconv.Itoa(len(q.args))
+	if q.dialect == db.DialectPostgres {
+		return "$" + n
+	}
+	return "?" + n
+}
+
+func (q *sqlQuery) String() string { return q.text.String() }
+
+const taskColumns = `id, title, description, priority, status, deadline, is_overdue,
+	created_at, updated_at, version, status_changed_at, completed_at, deleted_at, project_id, parent_id, recurrence,
+	next_occurrence_id`
+
+// closedStatuses lists the statuses for which TaskStatus.Closed is true, for
+// use in SQL.
+const closedStatuses = `('done', 'cancelled')`
+
+// rowScanner is satisfied by the row types of both pgx and database/sql.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask reads a row selected with taskColumns, followed by the columns read
+// into extra.
+func scanTask(row rowScanner, extra ...any) (models.Task, error) {
+	var task models.Task
+	dest := []any{&task.ID, &task.Title, &task.Description, &task.Priority, &task.Status,
+		&task.Deadline, &task.IsOverdue, &task.CreatedAt, &task.UpdatedAt, &task.Version, &task.StatusChangedAt,
+		&task.CompletedAt, &task.DeletedAt, &task.ProjectID, &task.ParentID, &task.Recurrence,
+		&task.NextOccurrenceID}
+	err := row.Scan(append(dest, extra...)...)
+	return task, err
+}
+
+// newTask returns a task just inserted, which has no tags or dependencies
+// yet.
+func newTask(task models.Task) *models.Task {
+	task.Tags = []string{}
+	task.BlockedBy = []int{}
+	return &task
+}
+
+// taskListQueries builds the page and total-count queries for a listing.
+func taskListQueries(dialect db.Dialect, userId int, params models.TaskListParams) (list, count *sqlQuery, err error) {
+	list = &sqlQuery{dialect: dialect}
+	count = &sqlQuery{dialect: dialect}
+
+	for _, q := range []*sqlQuery{list, count} {
+		conds := []string{"user_id = " + q.arg(userId), "deleted_at IS NULL"}
+		switch {
+		case params.WithTrashed:
+			conds = conds[:1]
+		case params.Trashed:
+			conds[1] = "deleted_at IS NOT NULL"
+		}
+		if len(params.Status) > 0 {
+			statuses := make([]string, len(params.Status))
+			for i, status := range params.Status {
+				statuses[i] = q.arg(status)
+			}
+			conds = append(conds, "status IN ("+strings.Join(statuses, ", ")+")")
+		}
+		if params.Completed != nil {
+			op := "<>"
+			if *params.Completed {
+				op = "="
+			}
+			conds = append(conds, "status "+op+" "+q.arg(models.StatusDone))
+		}
+		if params.Overdue != nil {
+			conds = append(conds, "is_overdue = "+q.arg(*params.Overdue))
+		}
+		if params.DeadlineBefore != nil {
+			conds = append(conds, "deadline < "+q.arg(*params.DeadlineBefore))
+		}
+		if params.DeadlineAfter != nil {
+			conds = append(conds, "deadline > "+q.arg(*params.DeadlineAfter))
+		}
+		if params.ProjectID != nil {
+			conds = append(conds, "project_id = "+q.arg(*params.ProjectID))
+		}
+		if params.ParentID != nil {
+			conds = append(conds, "parent_id = "+q.arg(*params.ParentID))
+		}
+		if len(params.Tags) > 0 {
+			conds = append(conds, tagCondition(q, params))
+		}
+		if q == list && params.After != "" {
+			cond, err := cursorCondition(q, params)
+			if err != nil {
+				return nil, nil, err
+			}
+			conds = append(conds, cond)
+		}
+		where := strings.Join(conds, " AND ")
+
+		if q == count {
+			fmt.Fprintf(&q.text, `SELECT COUNT(*) FROM tasks WHERE %s`, where)
+			continue
+		}
+		field, desc := params.SortField()
+		dir := "ASC"
+		if desc {
+			dir = "DESC"
+		}
+		order := "id " + dir
+		if key := sortExpr(q, field); key != "" {
+			order = key + " " + dir + ", " + order
+		}
+		fmt.Fprintf(&q.text, `SELECT %s FROM tasks WHERE %s ORDER BY %s LIMIT %s`,
+			taskColumns, where, order, q.arg(params.Limit+1))
+	}
+	return list, count, nil
+}
+
+// subtreeIDs is a recursive query for the IDs of the tasks whose root column
+// equals the first parameter and all of their descendants. The second
+// parameter is the user ID.
+func subtreeIDs(dialect db.Dialect, root string) string {
+	p := "?"
+	if dialect == db.DialectPostgres {
+		p = "$"
+	}
+	return fmt.Sprintf(`WITH RECURSIVE subtree (id) AS (
+			SELECT id FROM tasks WHERE %[2]s = %[1]s1 AND user_id = %[1]s2
+			UNION ALL
+			SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id
+		)
+		SELECT id FROM subtree`, p, root)
+}
+
+// tagCondition restricts a listing to tasks carrying all of params.Tags, or
+// any of them in TagModeAny.
+func tagCondition(q *sqlQuery, params models.TaskListParams) string {
+	names := make([]string, len(params.Tags))
+	for i, tag := range params.Tags {
+		names[i] = q.arg(tag)
+	}
+	sub := `SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id = tt.tag_id
+		WHERE g.name IN (` + strings.Join(names, ", ") + `)`
+	if params.TagMode != models.TagModeAny {
+		sub += ` GROUP BY tt.task_id HAVING COUNT(DISTINCT g.id) = ` + q.arg(len(params.Tags))
+	}
+	return "id IN (" + sub + ")"
+}
+
+// taskTagsQuery selects the (task_id, name) tag pairs of tasks.
+func taskTagsQuery(dialect db.Dialect, tasks []models.Task) *sqlQuery {
+	q := &sqlQuery{dialect: dialect}
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = q.arg(task.ID)
+	}
+	fmt.Fprintf(&q.text, `SELECT tt.task_id, g.name FROM task_tags tt JOIN tags g ON g.id = tt.tag_id
+		WHERE tt.task_id IN (%s) ORDER BY g.name`, strings.Join(ids, ", "))
+	return q
+}
+
+// setTaskTags assigns the tag names collected per task ID, leaving untagged
+// tasks with an empty list.
+func setTaskTags(tasks []models.Task, byTask map[int][]string) {
+	for i := range tasks {
+		tasks[i].Tags = byTask[tasks[i].ID]
+		if tasks[i].Tags == nil {
+			tasks[i].Tags = []string{}
+		}
+	}
+}
+
+// taskDependenciesQuery selects the (task_id, blocked_by_id, status) blockers
+// of tasks.
+func taskDependenciesQuery(dialect db.Dialect, tasks []models.Task) *sqlQuery {
+	q := &sqlQuery{dialect: dialect}
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = q.arg(task.ID)
+	}
+	fmt.Fprintf(&q.text, `SELECT d.task_id, d.blocked_by_id, b.status
+		FROM task_dependencies d JOIN tasks b ON b.id = d.blocked_by_id AND b.deleted_at IS NULL
+		WHERE d.task_id IN (%s) ORDER BY d.blocked_by_id`, strings.Join(ids, ", "))
+	return q
+}
+
+// taskDependency is a row of taskDependenciesQuery.
+type taskDependency struct {
+	taskId, blockedById int
+	status              models.TaskStatus
+}
+
+// setTaskDependencies assigns the blockers of each task and whether any of
+// them is still open.
+func setTaskDependencies(tasks []models.Task, deps []taskDependency) {
+	byTask := make(map[int][]taskDependency)
+	for _, dep := range deps {
+		byTask[dep.taskId] = append(byTask[dep.taskId], dep)
+	}
+	for i := range tasks {
+		tasks[i].BlockedBy = []int{}
+		tasks[i].IsBlocked = false
+		for _, dep := range byTask[tasks[i].ID] {
+			tasks[i].BlockedBy = append(tasks[i].BlockedBy, dep.blockedById)
+			tasks[i].IsBlocked = tasks[i].IsBlocked || !dep.status.Closed()
+		}
+	}
+}
+
+// blockerIDs is a recursive query for the IDs of every task a task waits on,
+// directly or through other blockers. Its first parameter is the task ID and
+// its second the user ID.
+func blockerIDs(dialect db.Dialect) string {
+	p := "?"
+	if dialect == db.DialectPostgres {
+		p = "$"
+	}
+	return fmt.Sprintf(`WITH RECURSIVE blockers (id) AS (
+			SELECT d.blocked_by_id FROM task_dependencies d JOIN tasks t ON t.id = d.task_id
+			WHERE d.task_id = %[1]s1 AND t.user_id = %[1]s2
+			UNION
+			SELECT d.blocked_by_id FROM task_dependencies d JOIN blockers b ON d.task_id = b.id
+		)
+		SELECT id FROM blockers`, p)
+}
+
+// sortExpr returns the SQL expression a field sorts by, or "" for fields
+// ordered by ID alone.
+func sortExpr(q *sqlQuery, field string) string {
+	switch field {
+	case models.TaskSortDeadline:
+		return "COALESCE(deadline, " + q.arg(deadlineSentinel) + ")"
+	case models.TaskSortTitle:
+		return "title"
+	case models.TaskSortPriority:
+		return "CASE priority WHEN 'low' THEN 0 WHEN 'normal' THEN 1 WHEN 'high' THEN 2 ELSE 3 END"
+	case models.TaskSortCreated:
+		return "created_at"
+	case models.TaskSortUpdated:
+		return "updated_at"
+	}
+	return ""
+}
+
+// cursorCondition restricts a listing to the tasks after params.After.
+func cursorCondition(q *sqlQuery, params models.TaskListParams) (string, error) {
+	cursor, err := decodeCursor(params.After)
+	if err != nil {
+		return "", err
+	}
+	field, desc := params.SortField()
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	id := q.arg(cursor.ID)
+	key := sortExpr(q, field)
+	if key == "" {
+		return fmt.Sprintf("id %s %s", op, id), nil
+	}
+
+	var value any = cursor.Key
+	switch field {
+	case models.TaskSortDeadline, models.TaskSortCreated, models.TaskSortUpdated:
+		t, err := time.Parse(cursorTimeFormat, cursor.Key)
+		if err != nil {
+			return "", ErrInvalidCursor
+		}
+		value = t
+	case models.TaskSortPriority:
+		rank, err := strconv.Atoi(cursor.Key)
+		if err != nil {
+			return "", ErrInvalidCursor
+		}
+		value = rank
+	}
+	v := q.arg(value)
+	return fmt.Sprintf("(%s %s %s OR (%s = %s AND id %s %s))", key, op, v, key, v, op, id), nil
+}
+
+// searchTerms splits a search query into the lower-cased words that the
+// substring-matching backends require to be present.
+func searchTerms(query string) []string {
+	return strings.Fields(strings.ToLower(query))
+}
+
+// likePattern escapes term for a LIKE ... ESCAPE '\' substring match.
+func likePattern(term string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(term) + "%"
+}
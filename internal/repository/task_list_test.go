@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, c := range []listCursor{
+		{ID: 1},
+		{Key: "2026-01-31T09:00:00.000000000Z", ID: 42},
+		{Key: `title with "quotes" and ünïcode`, ID: 7},
+	} {
+		got, err := decodeCursor(encodeCursor(c))
+		if err != nil || got != c {
+			t.Errorf("decodeCursor(encodeCursor(%+v)) = %+v, %v", c, got, err)
+		}
+	}
+}
+
+func TestDecodeCursorErrors(t *testing.T) {
+	for _, s := range []string{"", "!!!", "eyJpZCI6MX0=", "bm90IGpzb24"} {
+		if c, err := decodeCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q) = %+v, %v; want ErrInvalidCursor", s, c, err)
+		}
+	}
+}
+
+func TestSortKey(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	early := time.Date(2026, time.January, 1, 9, 0, 0, 0, time.UTC)
+	late := time.Date(2026, time.January, 1, 9, 0, 0, 500, time.UTC)
+	tests := []struct {
+		name        string
+		field       string
+		first, then models.Task
+	}{
+		{"deadline before none", models.TaskSortDeadline, models.Task{Deadline: &late}, models.Task{}},
+		{"deadline fraction", models.TaskSortDeadline, models.Task{Deadline: &early}, models.Task{Deadline: &late}},
+		{"deadline zone", models.TaskSortDeadline,
+			models.Task{Deadline: ptr(early.Add(-time.Hour).In(tokyo))}, models.Task{Deadline: &early}},
+		{"created", models.TaskSortCreated, models.Task{CreatedAt: early}, models.Task{CreatedAt: late}},
+		{"updated", models.TaskSortUpdated, models.Task{UpdatedAt: early.In(tokyo)}, models.Task{UpdatedAt: late}},
+		{"priority", models.TaskSortPriority, models.Task{Priority: models.PriorityHigh}, models.Task{Priority: models.PriorityUrgent}},
+		{"title", models.TaskSortTitle, models.Task{Title: "A"}, models.Task{Title: "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, then := sortKey(tt.first, tt.field), sortKey(tt.then, tt.field)
+			if first >= then {
+				t.Errorf("sortKey %q does not sort before %q", first, then)
+			}
+		})
+	}
+	if key := sortKey(models.Task{Title: "a"}, models.TaskSortID); key != "" {
+		t.Errorf("sortKey by ID = %q, want none", key)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// compareTasks orders tasks as a listing sorted by field ascending should,
+// without going through sort keys.
+func compareTasks(a, b models.Task, field string) int {
+	switch field {
+	case models.TaskSortTitle:
+		if c := strings.Compare(a.Title, b.Title); c != 0 {
+			return c
+		}
+	case models.TaskSortPriority:
+		if d := a.Priority.Rank() - b.Priority.Rank(); d != 0 {
+			return d
+		}
+	case models.TaskSortDeadline:
+		switch {
+		case a.Deadline == nil && b.Deadline == nil:
+		case a.Deadline == nil:
+			return 1
+		case b.Deadline == nil:
+			return -1
+		default:
+			if c := a.Deadline.Compare(*b.Deadline); c != 0 {
+				return c
+			}
+		}
+	case models.TaskSortCreated:
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+	case models.TaskSortUpdated:
+		if c := a.UpdatedAt.Compare(b.UpdatedAt); c != 0 {
+			return c
+		}
+	}
+	return a.ID - b.ID
+}
+
+func TestListPaging(t *testing.T) {
+	ctx := context.Background()
+	monday := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+	requests := []models.TaskRequest{
+		{Title: "write report", Priority: models.PriorityHigh, Deadline: &monday},
+		{Title: "book flights", Priority: models.PriorityLow},
+		{Title: "Call bank", Priority: models.PriorityUrgent, Deadline: ptr(monday.Add(-time.Hour))},
+		{Title: "book flights", Priority: models.PriorityHigh, Deadline: &monday},
+		{Title: "water plants", Priority: models.PriorityNormal},
+		{Title: "pay rent", Priority: models.PriorityUrgent, Deadline: ptr(monday.Add(48 * time.Hour))},
+		{Title: "renew passport", Priority: models.PriorityLow, Deadline: ptr(monday.Add(time.Minute))},
+	}
+
+	for name, repo := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			userId := createUser(t, repo, "alice")
+			otherId := createUser(t, repo, "bob")
+			ids := []int{}
+			for _, req := range requests {
+				ids = append(ids, createTask(t, repo, userId, req).ID)
+			}
+			createTask(t, repo, otherId, models.TaskRequest{Title: "not alice's"})
+			// Updating the earlier tasks makes updated order differ from created.
+			for _, i := range []int{2, 0} {
+				req := requests[i]
+				req.Description = "updated"
+				if err := repo.TaskRepo.Update(ctx, ids[i], userId, 0, req); err != nil {
+					t.Fatalf("Update failed: %v", err)
+				}
+			}
+
+			fields := []string{models.TaskSortID, models.TaskSortTitle, models.TaskSortPriority,
+				models.TaskSortDeadline, models.TaskSortCreated, models.TaskSortUpdated}
+			for _, field := range fields {
+				for _, sortParam := range []string{field, "-" + field} {
+					t.Run(sortParam, func(t *testing.T) {
+						all, err := repo.TaskRepo.List(ctx, userId, models.TaskListParams{Limit: 100, Sort: sortParam})
+						if err != nil {
+							t.Fatalf("List failed: %v", err)
+						}
+						if all.Total != len(requests) || len(all.Tasks) != len(requests) || all.NextCursor != "" {
+							t.Fatalf("List = %d of %d tasks, cursor %q; want all %d on one page",
+								len(all.Tasks), all.Total, all.NextCursor, len(requests))
+						}
+						want := append([]models.Task(nil), all.Tasks...)
+						sort.SliceStable(want, func(i, j int) bool {
+							c := compareTasks(want[i], want[j], field)
+							if sortParam[0] == '-' {
+								return c > 0
+							}
+							return c < 0
+						})
+						if got, w := taskIDs(all.Tasks), taskIDs(want); !slices.Equal(got, w) {
+							t.Fatalf("List sorted by %s = %v, want %v", sortParam, got, w)
+						}
+
+						paged := []models.Task{}
+						params := models.TaskListParams{Limit: 2, Sort: sortParam}
+						for page := 0; ; page++ {
+							if page > len(requests) {
+								t.Fatalf("paging does not end")
+							}
+							list, err := repo.TaskRepo.List(ctx, userId, params)
+							if err != nil {
+								t.Fatalf("List page %d failed: %v", page, err)
+							}
+							if list.Total != len(requests) {
+								t.Errorf("page %d has Total %d, want %d", page, list.Total, len(requests))
+							}
+							paged = append(paged, list.Tasks...)
+							if list.NextCursor == "" {
+								break
+							}
+							params.After = list.NextCursor
+						}
+						if got, w := taskIDs(paged), taskIDs(want); !slices.Equal(got, w) {
+							t.Errorf("pages sorted by %s = %v, want %v", sortParam, got, w)
+						}
+					})
+				}
+			}
+
+			_, err := repo.TaskRepo.List(ctx, userId, models.TaskListParams{Limit: 2, After: "!!!"})
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("List with a bad cursor = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func taskIDs(tasks []models.Task) []int {
+	ids := make([]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
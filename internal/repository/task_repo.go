@@ -4,129 +4,510 @@ import (
 	"context"
 	"errors"
 	"tasklist/db"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 
 	"tasklist/internal/models"
 )
 
-var ErrTaskNotFound = errors.New("task not found")
+var (
+	ErrTaskNotFound       = errors.New("task not found")
+	ErrDependencyNotFound = errors.New("dependency not found")
+	ErrParentInTrash      = errors.New("parent task is in the trash")
+	ErrVersionMismatch    = errors.New("task has been modified since it was read")
+	ErrStatusChanged      = errors.New("task status has changed meanwhile")
+)
 
+// Task stores tasks. Deleted tasks stay in the trash, where only List with
+// TaskListParams.Trashed or WithTrashed, Restore and the purge methods see
+// them.
 type Task interface {
-	Create(ctx context.Context, userId int, task models.TaskRequest) error
-	Complete(ctx context.Context, taskId int) error
-	List(ctx context.Context, userId int) ([]models.Task, error)
+	// Create stores a new task and returns it as persisted.
+	Create(ctx context.Context, userId int, task models.TaskRequest) (*models.Task, error)
+	// SetStatus moves a task from status from to status to, along with every
+	// open descendant when cascade is set. It fails with ErrStatusChanged
+	// unless the task is still at from. CompletedAt is set by moving to done
+	// and cleared by moving to any other status.
+	SetStatus(ctx context.Context, taskId, userId int, from, to models.TaskStatus, cascade bool) error
+	// SetNextOccurrence links a recurring task to the task created by
+	// completing it. The link is not shown to clients, so the version stays.
+	SetNextOccurrence(ctx context.Context, taskId, userId, nextId int) error
+	List(ctx context.Context, userId int, params models.TaskListParams) (*models.TaskList, error)
+	Search(ctx context.Context, userId int, query string, limit int) ([]models.Task, error)
 	GetByID(ctx context.Context, taskId, userId int) (*models.Task, error)
-	Update(ctx context.Context, taskId, userId int, task models.TaskRequest) error
-	Delete(ctx context.Context, id int) error
+	// Descendants returns the subtasks of a task at every depth, without tags
+	// or dependencies.
+	Descendants(ctx context.Context, taskId, userId int) ([]models.Task, error)
+	// Update and Delete fail with ErrVersionMismatch unless the task is at
+	// version, which may be 0 to skip the check.
+	Update(ctx context.Context, taskId, userId, version int, task models.TaskRequest) error
+	// Delete moves a task and its subtasks to the trash. Restore brings back
+	// a trashed task along with the subtasks deleted with it.
+	Delete(ctx context.Context, taskId, userId, version int) error
+	Restore(ctx context.Context, taskId, userId int) error
+	// Purge permanently deletes a trashed task, EmptyTrash every trashed task
+	// of a user, and PurgeDeleted every task trashed before a time.
+	Purge(ctx context.Context, taskId, userId int) error
+	EmptyTrash(ctx context.Context, userId int) (int, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
+
+	// Changes returns up to limit+1 of the changes to the tasks of a user
+	// numbered after since, trashed and purged tasks included.
+	// PurgeTombstones drops the tombstones of tasks purged before a time.
+	Changes(ctx context.Context, userId int, since int64, limit int) (*models.TaskChangeLog, error)
+	PurgeTombstones(ctx context.Context, before time.Time) (int, error)
+
+	// AddDependency marks taskId as blocked by blockerId; both must belong to
+	// userId. Blockers returns the IDs of the tasks taskId waits on, directly
+	// or transitively.
+	AddDependency(ctx context.Context, taskId, blockerId, userId int) error
+	RemoveDependency(ctx context.Context, taskId, blockerId, userId int) error
+	Blockers(ctx context.Context, taskId, userId int) ([]int, error)
 
 	ListAll(ctx context.Context) ([]models.Task, error)
-	MarkOverdued(ctx context.Context, taskId int) error
+	// MarkOverdued marks a live, open task overdue. It reports false, leaving
+	// the task alone, when the task was closed, trashed or already marked
+	// since it was listed.
+	MarkOverdued(ctx context.Context, taskId int) (bool, error)
 }
 type TaskRepo struct {
-	db *db.Database
+	db pgConn
 }
 
 func NewTaskRepo(db *db.Database) *TaskRepo {
-	return &TaskRepo{db: db}
+	return &TaskRepo{db: db.Pool}
+}
+
+func (r *TaskRepo) Create(ctx context.Context, userId int, task models.TaskRequest) (*models.Task, error) {
+	query := `INSERT INTO tasks (user_id, title, description, priority, deadline, project_id, parent_id, recurrence)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ` + taskColumns
+	created, err := scanTask(r.db.QueryRow(ctx, query, userId, task.Title, task.Description, task.Priority,
+		task.Deadline, task.ProjectID, task.ParentID, task.Recurrence))
+	if err != nil {
+		return nil, err
+	}
+	return newTask(created), nil
+}
+func (r *TaskRepo) SetStatus(ctx context.Context, taskId, userId int, from, to models.TaskStatus, cascade bool) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := lockTaskStatus(ctx, tx, taskId, userId, from); err != nil {
+			return err
+		}
+		set := `update tasks set status=$3, status_changed_at=now(),
+			completed_at=case when $3='done' then now() end, updated_at=now(), version=version+1 `
+		query := set + `where id=$1 and user_id=$2`
+		if cascade {
+			query = set + `where id in (` + subtreeIDs(db.DialectPostgres, "id") + `) and deleted_at is null
+				and (id=$1 or status not in ` + closedStatuses + `)`
+		}
+		_, err := tx.Exec(ctx, query, taskId, userId, string(to))
+		return err
+	})
+}
+
+func (r *TaskRepo) SetNextOccurrence(ctx context.Context, taskId, userId, nextId int) error {
+	query := `update tasks set next_occurrence_id=$3 where id=$1 and user_id=$2`
+	rows, err := r.db.Exec(ctx, query, taskId, userId, nextId)
+	if err != nil {
+		return err
+	}
+	if rows.RowsAffected() == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
 }
 
-func (r *TaskRepo) Create(ctx context.Context, userId int, task models.TaskRequest) error {
-	query := `INSERT INTO tasks (user_id,title, deadline) VALUES ($1, $2, $3) RETURNING id`
-	_, err := r.db.Pool.Exec(ctx, query, userId, task.Title, task.Deadline)
-	return err
+func (r *TaskRepo) List(ctx context.Context, userId int, params models.TaskListParams) (*models.TaskList, error) {
+	list, count, err := taskListQueries(db.DialectPostgres, userId, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, count.String(), count.args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	tasks, err := r.queryTasks(ctx, list.String(), list.args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadDetails(ctx, tasks); err != nil {
+		return nil, err
+	}
+	return newTaskList(tasks, total, params), nil
+}
+
+func (r *TaskRepo) Search(ctx context.Context, userId int, query string, limit int) ([]models.Task, error) {
+	stmt := `SELECT ` + taskColumns + `
+		FROM tasks, websearch_to_tsquery('simple', $2) q
+		WHERE user_id = $1 AND deleted_at IS NULL AND search @@ q
+		ORDER BY ts_rank(search, q) DESC, id DESC
+		LIMIT $3`
+	tasks, err := r.queryTasks(ctx, stmt, userId, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return tasks, r.loadDetails(ctx, tasks)
 }
-func (r *TaskRepo) Complete(ctx context.Context, taskId int) error {
-	query := `update tasks set completed=true where id=$1`
-	_, err := r.db.Pool.Exec(ctx, query, taskId)
-	return err
+
+func (r *TaskRepo) ListAll(ctx context.Context) ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks
+		where is_overdue=false and status not in ` + closedStatuses + ` and deleted_at is null`
+	return r.queryTasks(ctx, query)
 }
 
-func (r *TaskRepo) List(ctx context.Context, userId int) ([]models.Task, error) {
-	query := `SELECT id, title, completed, deadline, is_overdue
+func (r *TaskRepo) GetByID(ctx context.Context, id, userId int) (*models.Task, error) {
+	query := `SELECT ` + taskColumns + `
 		FROM tasks
-		where user_id = $1
-		ORDER BY id DESC`
+		WHERE id=$1 and user_id=$2 and deleted_at is null`
+	task, err := scanTask(r.db.QueryRow(ctx, query, id, userId))
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+	tasks := []models.Task{task}
+	if err := r.loadDetails(ctx, tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+func (r *TaskRepo) Descendants(ctx context.Context, taskId, userId int) ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks
+		WHERE id IN (` + subtreeIDs(db.DialectPostgres, "id") + `) AND id <> $1 AND deleted_at IS NULL
+		ORDER BY id`
+	return r.queryTasks(ctx, query, taskId, userId)
+}
+
+func (r *TaskRepo) Update(ctx context.Context, taskId, userId, version int, task models.TaskRequest) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := lockTaskVersion(ctx, tx, taskId, userId, version); err != nil {
+			return err
+		}
+		query := `UPDATE tasks SET title=$1, description=$2, priority=$3, deadline=$4, project_id=$5, parent_id=$6,
+			recurrence=$7, updated_at=now(), version=version+1
+			WHERE id=$8 and user_id=$9`
+		_, err := tx.Exec(ctx, query, task.Title, task.Description, task.Priority, task.Deadline,
+			task.ProjectID, task.ParentID, task.Recurrence, taskId, userId)
+		return err
+	})
+}
+
+func (r *TaskRepo) Delete(ctx context.Context, taskId, userId, version int) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := lockTaskVersion(ctx, tx, taskId, userId, version); err != nil {
+			return err
+		}
+		query := `UPDATE tasks SET deleted_at=now(), updated_at=now(), version=version+1
+			WHERE id IN (` + subtreeIDs(db.DialectPostgres, "id") + `) AND deleted_at IS NULL`
+		_, err := tx.Exec(ctx, query, taskId, userId)
+		return err
+	})
+}
+
+// lockTaskVersion locks a live task of userId for the rest of tx, failing
+// with ErrVersionMismatch unless it is at version or version is 0.
+func lockTaskVersion(ctx context.Context, tx pgx.Tx, taskId, userId, version int) error {
+	var current int
+	query := `SELECT version FROM tasks WHERE id=$1 and user_id=$2 and deleted_at is null FOR UPDATE`
+	if err := tx.QueryRow(ctx, query, taskId, userId).Scan(&current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTaskNotFound
+		}
+		return err
+	}
+	if version != 0 && version != current {
+		return ErrVersionMismatch
+	}
+	return nil
+}
 
-	rows, err := r.db.Pool.Query(ctx, query, userId)
+// lockTaskStatus locks a live task of userId for the rest of tx, failing
+// with ErrStatusChanged unless it is at status.
+func lockTaskStatus(ctx context.Context, tx pgx.Tx, taskId, userId int, status models.TaskStatus) error {
+	var current models.TaskStatus
+	query := `SELECT status FROM tasks WHERE id=$1 and user_id=$2 and deleted_at is null FOR UPDATE`
+	if err := tx.QueryRow(ctx, query, taskId, userId).Scan(&current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTaskNotFound
+		}
+		return err
+	}
+	if current != status {
+		return ErrStatusChanged
+	}
+	return nil
+}
+
+func (r *TaskRepo) Restore(ctx context.Context, taskId, userId int) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var deletedAt time.Time
+		var parentInTrash bool
+		query := `SELECT t.deleted_at, p.deleted_at IS NOT NULL
+			FROM tasks t LEFT JOIN tasks p ON p.id = t.parent_id
+			WHERE t.id=$1 and t.user_id=$2 and t.deleted_at IS NOT NULL`
+		if err := tx.QueryRow(ctx, query, taskId, userId).Scan(&deletedAt, &parentInTrash); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrTaskNotFound
+			}
+			return err
+		}
+		if parentInTrash {
+			return ErrParentInTrash
+		}
+		restore := `UPDATE tasks SET deleted_at=NULL, updated_at=now(), version=version+1
+			WHERE id IN (` + subtreeIDs(db.DialectPostgres, "id") + `) AND deleted_at=$3`
+		_, err := tx.Exec(ctx, restore, taskId, userId, deletedAt)
+		return err
+	})
+}
+
+func (r *TaskRepo) Purge(ctx context.Context, taskId, userId int) error {
+	query := `DELETE FROM tasks WHERE id=$1 and user_id=$2 and deleted_at IS NOT NULL`
+	rows, err := r.db.Exec(ctx, query, taskId, userId)
+	if err != nil {
+		return err
+	}
+	if rows.RowsAffected() == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+func (r *TaskRepo) EmptyTrash(ctx context.Context, userId int) (int, error) {
+	return r.purgeTrash(ctx, `user_id=$1`, userId)
+}
+
+func (r *TaskRepo) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	return r.purgeTrash(ctx, `deleted_at < $1`, before)
+}
+
+// purgeTrash permanently deletes the trashed tasks matching cond. The tasks
+// are counted first because subtasks removed by the parent_id cascade are not
+// reported as affected rows.
+func (r *TaskRepo) purgeTrash(ctx context.Context, cond string, arg any) (int, error) {
+	var purged int
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		where := ` FROM tasks WHERE deleted_at IS NOT NULL and ` + cond
+		if err := tx.QueryRow(ctx, `SELECT count(*)`+where, arg).Scan(&purged); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE`+where, arg)
+		return err
+	})
+	return purged, err
+}
+
+// Changes reads the change counter of the user first, and its horizon last
+// so that it covers the tombstones dropped meanwhile.
+func (r *TaskRepo) Changes(ctx context.Context, userId int, since int64, limit int) (*models.TaskChangeLog, error) {
+	log := &models.TaskChangeLog{}
+	if err := r.db.QueryRow(ctx, `SELECT change_seq FROM users WHERE id=$1`, userId).Scan(&log.Latest); err != nil {
+		return nil, err
+	}
+	tasks, tombstones := taskChangeQueries(db.DialectPostgres, userId, since, log.Latest, limit)
+	changed, err := r.queryChangedTasks(ctx, tasks)
+	if err != nil {
+		return nil, err
+	}
+	purged, err := r.queryTombstones(ctx, tombstones)
+	if err != nil {
+		return nil, err
+	}
+	log.Changes = sortTaskChanges(append(changed, purged...), limit)
+	err = r.db.QueryRow(ctx, `SELECT sync_horizon FROM users WHERE id=$1`, userId).Scan(&log.Horizon)
+	return log, err
+}
+
+func (r *TaskRepo) queryChangedTasks(ctx context.Context, q *sqlQuery) ([]models.TaskChange, error) {
+	rows, err := r.db.Query(ctx, q.String(), q.args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	tasks := []models.Task{}
+	var tasks []models.Task
+	var seqs []int64
 	for rows.Next() {
-		var task models.Task
-		if err := rows.Scan(&task.ID, &task.Title, &task.Completed, &task.Deadline, &task.IsOverdue); err != nil {
+		var seq int64
+		task, err := scanTask(rows, &seq)
+		if err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, task)
+		seqs = append(seqs, seq)
 	}
-	return tasks, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if err := r.loadDetails(ctx, tasks); err != nil {
+		return nil, err
+	}
+	changes := make([]models.TaskChange, len(tasks))
+	for i := range tasks {
+		changes[i] = models.TaskChange{Seq: seqs[i], Task: &tasks[i]}
+	}
+	return changes, nil
 }
 
-func (r *TaskRepo) ListAll(ctx context.Context) ([]models.Task, error) {
-	query := `SELECT id, title, completed, deadline, is_overdue FROM tasks where is_overdue=false and completed=false`
-
-	rows, err := r.db.Pool.Query(ctx, query)
+func (r *TaskRepo) queryTombstones(ctx context.Context, q *sqlQuery) ([]models.TaskChange, error) {
+	rows, err := r.db.Query(ctx, q.String(), q.args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	tasks := []models.Task{}
+	var changes []models.TaskChange
 	for rows.Next() {
-		var task models.Task
-		if err := rows.Scan(&task.ID, &task.Title, &task.Completed, &task.Deadline, &task.IsOverdue); err != nil {
+		change := models.TaskChange{Purged: &models.TaskTombstone{}}
+		if err := rows.Scan(&change.Purged.ID, &change.Purged.DeletedAt, &change.Seq); err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, task)
+		changes = append(changes, change)
 	}
-	return tasks, nil
+	return changes, rows.Err()
 }
 
-func (r *TaskRepo) GetByID(ctx context.Context, id, userId int) (*models.Task, error) {
-	var task models.Task
-	query := `SELECT id, title, completed, deadline, is_overdue
-		FROM tasks
-		WHERE id=$1 and user_id=$2`
-	err := r.db.Pool.QueryRow(ctx, query, id, userId).Scan(&task.ID, &task.Title, &task.Completed, &task.Deadline, &task.IsOverdue)
+// PurgeTombstones raises the horizon of each user to the last change it
+// drops, so that clients that have not synced it are told to start over.
+func (r *TaskRepo) PurgeTombstones(ctx context.Context, before time.Time) (int, error) {
+	var purged int64
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		horizon := `UPDATE users u SET sync_horizon = t.seq
+			FROM (SELECT user_id, max(change_seq) AS seq FROM task_tombstones WHERE deleted_at < $1 GROUP BY user_id) t
+			WHERE u.id = t.user_id and t.seq > u.sync_horizon`
+		if _, err := tx.Exec(ctx, horizon, before); err != nil {
+			return err
+		}
+		rows, err := tx.Exec(ctx, `DELETE FROM task_tombstones WHERE deleted_at < $1`, before)
+		if err != nil {
+			return err
+		}
+		purged = rows.RowsAffected()
+		return nil
+	})
+	return int(purged), err
+}
+
+func (r *TaskRepo) AddDependency(ctx context.Context, taskId, blockerId, userId int) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := touchTask(ctx, tx, taskId, userId); err != nil {
+			return err
+		}
+		query := `INSERT INTO task_dependencies (task_id, blocked_by_id)
+			SELECT $1, id FROM tasks WHERE id=$2 and user_id=$3 and deleted_at is null
+			ON CONFLICT DO NOTHING`
+		_, err := tx.Exec(ctx, query, taskId, blockerId, userId)
+		return err
+	})
+}
+
+func (r *TaskRepo) RemoveDependency(ctx context.Context, taskId, blockerId, userId int) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := touchTask(ctx, tx, taskId, userId); err != nil {
+			return err
+		}
+		query := `DELETE FROM task_dependencies WHERE task_id=$1 and blocked_by_id=$2`
+		rows, err := tx.Exec(ctx, query, taskId, blockerId)
+		if err != nil {
+			return err
+		}
+		if rows.RowsAffected() == 0 {
+			return ErrDependencyNotFound
+		}
+		return nil
+	})
+}
+
+func (r *TaskRepo) Blockers(ctx context.Context, taskId, userId int) ([]int, error) {
+	rows, err := r.db.Query(ctx, blockerIDs(db.DialectPostgres), taskId, userId)
 	if err != nil {
-		return nil, ErrTaskNotFound
+		return nil, err
 	}
-	return &task, nil
+	return pgx.CollectRows(rows, pgx.RowTo[int])
 }
 
-func (r *TaskRepo) Update(ctx context.Context, taskId, userId int, task models.TaskRequest) error {
-	query := `UPDATE tasks SET title=$1, deadline=$2 WHERE id=$3 and user_id=$4`
-	rows, err := r.db.Pool.Exec(ctx, query, task.Title, task.Deadline, taskId, userId)
+func (r *TaskRepo) MarkOverdued(ctx context.Context, taskId int) (bool, error) {
+	query := `update tasks set is_overdue=true, updated_at=now(), version=version+1
+		where id=$1 and is_overdue=false and status not in ` + closedStatuses + ` and deleted_at is null`
+	rows, err := r.db.Exec(ctx, query, taskId)
 	if err != nil {
-		return err
+		return false, err
 	}
-	if rows.RowsAffected() == 0 {
-		return ErrTaskNotFound
+	return rows.RowsAffected() > 0, nil
+}
+
+func (r *TaskRepo) queryTasks(ctx context.Context, query string, args ...any) ([]models.Task, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
 }
 
-func (r *TaskRepo) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM tasks WHERE id=$1`
-	rows, err := r.db.Pool.Exec(ctx, query, id)
+// loadDetails fills in the tags and dependencies of tasks.
+func (r *TaskRepo) loadDetails(ctx context.Context, tasks []models.Task) error {
+	if err := r.loadTags(ctx, tasks); err != nil {
+		return err
+	}
+	return r.loadDependencies(ctx, tasks)
+}
+
+func (r *TaskRepo) loadTags(ctx context.Context, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	q := taskTagsQuery(db.DialectPostgres, tasks)
+	rows, err := r.db.Query(ctx, q.String(), q.args...)
 	if err != nil {
 		return err
 	}
-	if rows.RowsAffected() == 0 {
-		return ErrTaskNotFound
+	defer rows.Close()
+
+	byTask := make(map[int][]string)
+	for rows.Next() {
+		var taskId int
+		var name string
+		if err := rows.Scan(&taskId, &name); err != nil {
+			return err
+		}
+		byTask[taskId] = append(byTask[taskId], name)
 	}
-	return nil
+	setTaskTags(tasks, byTask)
+	return rows.Err()
 }
 
-func (r *TaskRepo) MarkOverdued(ctx context.Context, taskId int) error {
-	query := `update tasks set is_overdue=true where id=$1`
-	rows, err := r.db.Pool.Exec(ctx, query, taskId)
+func (r *TaskRepo) loadDependencies(ctx context.Context, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	q := taskDependenciesQuery(db.DialectPostgres, tasks)
+	rows, err := r.db.Query(ctx, q.String(), q.args...)
 	if err != nil {
 		return err
 	}
-	if rows.RowsAffected() == 0 {
-		return ErrTaskNotFound
+	defer rows.Close()
+
+	var deps []taskDependency
+	for rows.Next() {
+		var dep taskDependency
+		if err := rows.Scan(&dep.taskId, &dep.blockedById, &dep.status); err != nil {
+			return err
+		}
+		deps = append(deps, dep)
 	}
-	return nil
+	setTaskDependencies(tasks, deps)
+	return rows.Err()
 }
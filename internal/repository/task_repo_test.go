@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tasklist/internal/models"
+)
+
+func TestSetStatus(t *testing.T) {
+	ctx := context.Background()
+	for name, repo := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			userId := createUser(t, repo, "alice")
+			task := createTask(t, repo, userId, models.TaskRequest{Title: "a"})
+
+			tests := []struct {
+				from, to models.TaskStatus
+				want     error
+			}{
+				{models.StatusTodo, models.StatusDone, nil},
+				{models.StatusTodo, models.StatusDone, ErrStatusChanged},
+				{models.StatusDone, models.StatusTodo, nil},
+				{models.StatusInProgress, models.StatusCancelled, ErrStatusChanged},
+			}
+			for _, tt := range tests {
+				err := repo.TaskRepo.SetStatus(ctx, task.ID, userId, tt.from, tt.to, false)
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("SetStatus(%s, %s) = %v, want %v", tt.from, tt.to, err, tt.want)
+				}
+			}
+			got, err := repo.TaskRepo.GetByID(ctx, task.ID, userId)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if got.Status != models.StatusTodo || got.CompletedAt != nil {
+				t.Errorf("task is %s, completed at %v; want todo and not completed", got.Status, got.CompletedAt)
+			}
+		})
+	}
+}
+
+func TestMarkOverdued(t *testing.T) {
+	ctx := context.Background()
+	for name, repo := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			userId := createUser(t, repo, "alice")
+			open := createTask(t, repo, userId, models.TaskRequest{Title: "open"})
+			done := createTask(t, repo, userId, models.TaskRequest{Title: "done"})
+			cancelled := createTask(t, repo, userId, models.TaskRequest{Title: "cancelled"})
+			trashed := createTask(t, repo, userId, models.TaskRequest{Title: "trashed"})
+			if err := repo.TaskRepo.SetStatus(ctx, done.ID, userId, models.StatusTodo, models.StatusDone, false); err != nil {
+				t.Fatalf("SetStatus failed: %v", err)
+			}
+			if err := repo.TaskRepo.SetStatus(ctx, cancelled.ID, userId, models.StatusTodo, models.StatusCancelled, false); err != nil {
+				t.Fatalf("SetStatus failed: %v", err)
+			}
+			if err := repo.TaskRepo.Delete(ctx, trashed.ID, userId, 0); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+
+			// A task left alone keeps its version; trashed and missing tasks
+			// cannot be read back.
+			tests := []struct {
+				name        string
+				id          int
+				want        bool
+				wantVersion int
+			}{
+				{"open", open.ID, true, 2},
+				{"already overdue", open.ID, false, 2},
+				{"done", done.ID, false, 2},
+				{"cancelled", cancelled.ID, false, 2},
+				{"trashed", trashed.ID, false, 0},
+				{"missing", trashed.ID + 100, false, 0},
+			}
+			for _, tt := range tests {
+				marked, err := repo.TaskRepo.MarkOverdued(ctx, tt.id)
+				if err != nil || marked != tt.want {
+					t.Fatalf("MarkOverdued(%s) = %v, %v; want %v", tt.name, marked, err, tt.want)
+				}
+				if tt.wantVersion == 0 {
+					continue
+				}
+				task, err := repo.TaskRepo.GetByID(ctx, tt.id, userId)
+				if err != nil {
+					t.Fatalf("GetByID failed: %v", err)
+				}
+				if task.Version != tt.wantVersion || task.IsOverdue != (tt.id == open.ID) {
+					t.Errorf("%s task is at version %d, overdue %v", tt.name, task.Version, task.IsOverdue)
+				}
+			}
+		})
+	}
+}
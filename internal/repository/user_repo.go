@@ -7,23 +7,32 @@ import (
 	"tasklist/internal/models"
 )
 
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("user already exists")
+)
+
 type User interface {
 	Create(ctx context.Context, user *models.User) (int, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	UpdatePassword(ctx context.Context, userId int, password string) error
 }
 
 type UserRepo struct {
-	db *db.Database
+	db pgConn
 }
 
 func NewUserRepo(db *db.Database) *UserRepo {
-	return &UserRepo{db: db}
+	return &UserRepo{db: db.Pool}
 }
 
 func (r *UserRepo) Create(ctx context.Context, user *models.User) (int, error) {
 	var userId int
 	query := `INSERT INTO users (username, password) VALUES ($1,$2) RETURNING id`
-	if err := r.db.Pool.QueryRow(ctx, query, user.Username, user.Password).Scan(&userId); err != nil {
+	if err := r.db.QueryRow(ctx, query, user.Username, user.Password).Scan(&userId); err != nil {
+		if isUniqueViolation(err) {
+			return userId, ErrUserExists
+		}
 		return userId, err
 	}
 
@@ -35,9 +44,20 @@ func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*models.
 
 	query := `SELECT id, username, password FROM users WHERE username=$1`
 
-	row := r.db.Pool.QueryRow(ctx, query, username)
+	row := r.db.QueryRow(ctx, query, username)
 	if err := row.Scan(&user.ID, &user.Username, &user.Password); err != nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	return &user, nil
 }
+
+func (r *UserRepo) UpdatePassword(ctx context.Context, userId int, password string) error {
+	tag, err := r.db.Exec(ctx, `UPDATE users SET password=$1 WHERE id=$2`, password, userId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
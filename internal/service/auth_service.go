@@ -3,8 +3,12 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
+	"sync"
 	"tasklist/pkg/config"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 
 	"tasklist/internal/models"
@@ -12,29 +16,79 @@ import (
 	"tasklist/pkg/auth"
 )
 
+var (
+	ErrUserExists          = repository.ErrUserExists
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used; the session has been revoked")
+)
+
+// dummyHashes holds a hash of no one's password for each bcrypt cost. Logins
+// to unknown usernames are checked against it, so that they take as long as
+// wrong passwords and do not tell which usernames exist.
+var dummyHashes sync.Map
+
+func dummyHash(cost int) []byte {
+	if hash, ok := dummyHashes.Load(cost); ok {
+		return hash.([]byte)
+	}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not a password of anyone"), cost)
+	dummyHashes.Store(cost, hash)
+	return hash
+}
+
 type Auth interface {
-	Register(ctx context.Context, username, password string) (string, error)
-	Login(ctx context.Context, username, password string) (string, error)
+	Register(ctx context.Context, username, password string) (*models.AuthResponse, error)
+	Login(ctx context.Context, username, password, clientIP string) (*models.AuthResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.AuthResponse, error)
+	Logout(ctx context.Context, sessionId string) error
+	SessionActive(ctx context.Context, sessionId string) (bool, error)
 }
 type AuthService struct {
-	repo repository.User
-	cfg  *config.Config
+	repos    *repository.Repository
+	repo     repository.User
+	tokens   repository.RefreshToken
+	throttle *loginThrottle
+	keys     *auth.Keyset
+	cfg      *config.Config
+	log      *logrus.Logger
 }
 
-func NewAuthService(r repository.User, cfg *config.Config) *AuthService {
+// NewAuthService returns the service of repos, counting failed logins in
+// logins, which need not be repos.LoginRepo.
+func NewAuthService(repos *repository.Repository, logins repository.LoginFailure, keys *auth.Keyset, cfg *config.Config, log *logrus.Logger) *AuthService {
+	// The dummy hash is made up front, lest the first unknown username take
+	// longer than a wrong password.
+	dummyHash(cfg.BcryptCost)
 	return &AuthService{
-		repo: r,
-		cfg:  cfg,
+		repos:    repos,
+		repo:     repos.UserRepo,
+		tokens:   repos.TokenRepo,
+		throttle: &loginThrottle{failures: logins, cfg: cfg},
+		keys:     keys,
+		cfg:      cfg,
+		log:      log,
 	}
 }
 
-func (s *AuthService) Register(ctx context.Context, username, password string) (string, error) {
-	if username == "" || password == "" {
-		return "", errors.New("username or password is empty")
+// withinTx calls fn with a service whose changes are committed together.
+func (s *AuthService) withinTx(ctx context.Context, fn func(tx *AuthService) error) error {
+	return s.repos.WithinTx(ctx, func(repos *repository.Repository) error {
+		return fn(NewAuthService(repos, s.throttle.failures, s.keys, s.cfg, s.log))
+	})
+}
+
+func (s *AuthService) Register(ctx context.Context, username, password string) (*models.AuthResponse, error) {
+	username = normalizeUsername(username)
+	if err := validateUsername(username); err != nil {
+		return nil, err
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err := checkPassword(s.cfg, username, password); err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cfg.BcryptCost)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	user := models.User{
@@ -43,26 +97,161 @@ func (s *AuthService) Register(ctx context.Context, username, password string) (
 	}
 	userId, err := s.repo.Create(ctx, &user)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	token, err := auth.GenerateToken(userId, s.cfg)
+	return s.startSession(ctx, userId)
+}
+
+// Login checks the credentials of a user logging in from clientIP, which may
+// be empty, and opens a session. It fails with ErrInvalidCredentials whether
+// the username or the password is wrong, so as not to tell which usernames
+// exist, and with *LoginThrottledError after too many failures. A password
+// hashed with a lower bcrypt cost than configured is rehashed.
+func (s *AuthService) Login(ctx context.Context, username, password, clientIP string) (*models.AuthResponse, error) {
+	if username == "" || password == "" {
+		return nil, ValidationError("username or password is empty")
+	}
+	typed := strings.TrimSpace(username)
+	username = normalizeUsername(username)
+	keys := s.throttle.keys(username, clientIP)
+	reserved, err := s.throttle.reserve(ctx, keys, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userByLogin(ctx, typed, username)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		// The password was never checked, so the attempt is not held
+		// against the user.
+		return nil, errors.Join(err, s.throttle.refund(ctx, keys, reserved))
+	}
 	if err != nil {
-		return "", err
+		bcrypt.CompareHashAndPassword(dummyHash(s.cfg.BcryptCost), []byte(password))
+		return nil, ErrInvalidCredentials
 	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := s.throttle.succeed(ctx, keys, reserved); err != nil {
+		return nil, err
+	}
+	if cost, err := bcrypt.Cost([]byte(user.Password)); err == nil && cost < s.cfg.BcryptCost {
+		// The old hash still works, so a failed rehash is retried at the
+		// next login rather than failing this one.
+		if err := s.rehash(ctx, user.ID, password); err != nil {
+			s.log.WithError(err).WithField("user_id", user.ID).Warn("rehash password failed")
+		}
+	}
+	return s.startSession(ctx, user.ID)
+}
 
-	return token, nil
+// rehash stores password hashed with the configured bcrypt cost.
+func (s *AuthService) rehash(ctx context.Context, userId int, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cfg.BcryptCost)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdatePassword(ctx, userId, string(hash))
 }
-func (s *AuthService) Login(ctx context.Context, username, password string) (string, error) {
-	if username == "" || password == "" {
-		return "", errors.New("username or password is empty")
+
+// userByLogin finds the user logging in with the username typed, trimmed,
+// and normalized as username. Legacy usernames that collided once normalized
+// were left as registered, so the username as typed is looked up first.
+func (s *AuthService) userByLogin(ctx context.Context, typed, username string) (*models.User, error) {
+	if typed != username {
+		user, err := s.repo.GetByUsername(ctx, typed)
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			return user, err
+		}
+	}
+	return s.repo.GetByUsername(ctx, username)
+}
+
+// Refresh exchanges a refresh token for a new access token and the refresh
+// token that replaces it. A refresh token works once: presenting it again,
+// as whoever stole it would, revokes its session and fails with
+// ErrRefreshTokenReused.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.AuthResponse, error) {
+	hash := auth.HashRefreshToken(refreshToken)
+	var resp *models.AuthResponse
+	var reused string
+	err := s.withinTx(ctx, func(tx *AuthService) error {
+		token, err := tx.tokens.GetByHash(ctx, hash)
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if token.RevokedAt != nil || !time.Now().Before(token.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+		fresh := false
+		if token.UsedAt == nil {
+			if fresh, err = tx.tokens.MarkUsed(ctx, token.ID); err != nil {
+				return err
+			}
+		}
+		if !fresh {
+			reused = token.SessionID
+			return ErrRefreshTokenReused
+		}
+		resp, err = tx.issueTokens(ctx, token.UserID, token.SessionID)
+		return err
+	})
+	// The revocation must outlive the rollback of the failed refresh.
+	if reused != "" {
+		if err := s.tokens.RevokeSession(ctx, reused); err != nil {
+			return nil, err
+		}
 	}
-	user, err := s.repo.GetByUsername(ctx, username)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.New("password incorrect")
+	return resp, nil
+}
+
+// Logout revokes a login session, so that neither its refresh tokens nor its
+// access tokens are accepted any more.
+func (s *AuthService) Logout(ctx context.Context, sessionId string) error {
+	return s.tokens.RevokeSession(ctx, sessionId)
+}
+
+func (s *AuthService) SessionActive(ctx context.Context, sessionId string) (bool, error) {
+	return s.tokens.SessionActive(ctx, sessionId)
+}
+
+// startSession opens a login session for userId and issues its first tokens.
+func (s *AuthService) startSession(ctx context.Context, userId int) (*models.AuthResponse, error) {
+	sessionId, err := auth.NewSessionID()
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, userId, sessionId)
+}
+
+// issueTokens issues an access token and a refresh token in a session.
+func (s *AuthService) issueTokens(ctx context.Context, userId int, sessionId string) (*models.AuthResponse, error) {
+	access, err := s.keys.GenerateToken(userId, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.tokens.Create(ctx, models.RefreshToken{
+		SessionID: sessionId,
+		UserID:    userId,
+		TokenHash: auth.HashRefreshToken(refresh),
+		ExpiresAt: time.Now().Add(time.Duration(s.cfg.RefreshTtlDays) * 24 * time.Hour),
+	})
+	if err != nil {
+		return nil, err
 	}
-	return auth.GenerateToken(user.ID, s.cfg)
+	return &models.AuthResponse{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    s.cfg.JwtTtlMin * 60,
+	}, nil
 }
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"tasklist/internal/models"
+	"tasklist/internal/repository"
+	"tasklist/pkg/auth"
+	"tasklist/pkg/config"
+)
+
+var errDatabaseDown = errors.New("database is down")
+
+// failingUsers fails to look users up while down is set, and always fails
+// to store passwords.
+type failingUsers struct {
+	repository.User
+	down bool
+}
+
+func (r *failingUsers) UpdatePassword(ctx context.Context, userId int, password string) error {
+	return errDatabaseDown
+}
+
+func (r *failingUsers) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	if r.down {
+		return nil, errDatabaseDown
+	}
+	return r.User.GetByUsername(ctx, username)
+}
+
+func testAuthConfig() *config.Config {
+	return &config.Config{
+		JwtAlgorithm:       config.JwtHS256,
+		JwtSecret:          "test-secret",
+		JwtIssuer:          "tasklist",
+		JwtAudience:        "tasklist",
+		JwtTtlMin:          60,
+		RefreshTtlDays:     30,
+		PasswordMinLength:  8,
+		PasswordMinClasses: 2,
+		BcryptCost:         4,
+		LoginUserAttempts:  2,
+		LoginIPAttempts:    20,
+		LoginBackoffSec:    30,
+		LoginMaxLockoutSec: 900,
+		LoginWindowMin:     60,
+	}
+}
+
+func newTestAuthService(t *testing.T, repos *repository.Repository, cfg *config.Config) *AuthService {
+	t.Helper()
+	keys, err := auth.NewKeyset(cfg)
+	if err != nil {
+		t.Fatalf("NewKeyset failed: %v", err)
+	}
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return NewAuthService(repos, repository.NewMemoryLoginFailureRepo(), keys, cfg, log)
+}
+
+func TestLoginRefundsWhenLookupFails(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepository()
+	users := &failingUsers{User: repos.UserRepo}
+	repos.UserRepo = users
+	s := newTestAuthService(t, repos, testAuthConfig())
+	if _, err := s.Register(ctx, "alice", "correct-horse-9"); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	users.down = true
+	for i := 0; i < 5; i++ {
+		if _, err := s.Login(ctx, "alice", "correct-horse-9", "192.0.2.1"); !errors.Is(err, errDatabaseDown) {
+			t.Fatalf("login %d while the database is down = %v, want its error", i+1, err)
+		}
+	}
+	users.down = false
+	if _, err := s.Login(ctx, "alice", "correct-horse-9", "192.0.2.1"); err != nil {
+		t.Fatalf("login once the database is back = %v, want no lockout", err)
+	}
+}
+
+func TestLoginSurvivesFailedRehash(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepository()
+	repos.UserRepo = &failingUsers{User: repos.UserRepo}
+	cfg := testAuthConfig()
+	if _, err := newTestAuthService(t, repos, cfg).Register(ctx, "alice", "correct-horse-9"); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	// Raising the cost makes the registered hash due for a rehash, which
+	// cannot be stored.
+	cfg.BcryptCost++
+	if _, err := newTestAuthService(t, repos, cfg).Login(ctx, "alice", "correct-horse-9", "192.0.2.1"); err != nil {
+		t.Fatalf("login with a failing rehash = %v, want a session", err)
+	}
+}
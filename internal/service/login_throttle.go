@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"tasklist/internal/models"
+	"tasklist/internal/repository"
+	"tasklist/pkg/config"
+)
+
+// LoginThrottledError reports a login refused, without checking its
+// password, because of too many failed logins for the username or from the
+// client address.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed logins; retry in %s", time.Duration(e.RetryAfterSeconds())*time.Second)
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, as the
+// Retry-After header takes them.
+func (e *LoginThrottledError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// loginThrottle slows down password guessing. Past a number of failed logins
+// for a username, or from a client address, each further failure locks that
+// key out for twice as long as the previous one, up to a maximum. The count
+// starts over once a key has not failed for the failure window.
+type loginThrottle struct {
+	failures repository.LoginFailure
+	cfg      *config.Config
+}
+
+// throttleKey is a key that failed logins are counted under, with the number
+// of failures it is allowed before being locked out.
+type throttleKey struct {
+	key      string
+	attempts int
+}
+
+// keys returns the keys of a login: the normalized username, and the client
+// address unless it is unknown. An address gets more attempts, as many users
+// may share one.
+func (t *loginThrottle) keys(username, clientIP string) []throttleKey {
+	keys := []throttleKey{{key: "user:" + username, attempts: t.cfg.LoginUserAttempts}}
+	if clientIP != "" {
+		keys = append(keys, throttleKey{key: "ip:" + clientIP, attempts: t.cfg.LoginIPAttempts})
+	}
+	return keys
+}
+
+// reserve counts a login under each of keys as failed before its password is
+// checked, so that concurrent logins cannot all get past a check made before
+// any of them failed. While any key is locked out, it fails with
+// *LoginThrottledError, leaving the counts as they were.
+func (t *loginThrottle) reserve(ctx context.Context, keys []throttleKey, now time.Time) ([]models.LoginFailures, error) {
+	since := now.Add(-time.Duration(t.cfg.LoginWindowMin) * time.Minute)
+	var reserved []models.LoginFailures
+	for _, k := range keys {
+		lockout := func(failures int) time.Duration { return t.lockout(failures - k.attempts) }
+		f, ok, err := t.failures.Reserve(ctx, k.key, now, since, lockout)
+		if err == nil && !ok {
+			err = &LoginThrottledError{RetryAfter: f.LockedUntil.Sub(now)}
+		}
+		if err != nil {
+			return nil, errors.Join(err, t.refund(ctx, keys, reserved))
+		}
+		reserved = append(reserved, f)
+	}
+	return reserved, nil
+}
+
+// lockout returns how long a key is locked out after excess failures past
+// those it is allowed.
+func (t *loginThrottle) lockout(excess int) time.Duration {
+	if excess <= 0 {
+		return 0
+	}
+	lockout := time.Duration(t.cfg.LoginBackoffSec) * time.Second
+	maxLockout := time.Duration(t.cfg.LoginMaxLockoutSec) * time.Second
+	for i := 1; i < excess && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+	return min(lockout, maxLockout)
+}
+
+// refund takes back the logins reserved under the first keys.
+func (t *loginThrottle) refund(ctx context.Context, keys []throttleKey, reserved []models.LoginFailures) error {
+	for i, f := range reserved {
+		if err := t.failures.Refund(ctx, keys[i].key, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// succeed forgets the failures of the username of a successful login, which
+// reserve counted. Only the login itself is taken back from its address, so
+// that logging in to an account of one's own does not clear the way for
+// guessing the passwords of others.
+func (t *loginThrottle) succeed(ctx context.Context, keys []throttleKey, reserved []models.LoginFailures) error {
+	if err := t.failures.Reset(ctx, keys[0].key); err != nil {
+		return err
+	}
+	return t.refund(ctx, keys[1:], reserved[1:])
+}
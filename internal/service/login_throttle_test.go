@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tasklist/internal/repository"
+	"tasklist/pkg/config"
+)
+
+func newTestThrottle() *loginThrottle {
+	return &loginThrottle{
+		failures: repository.NewMemoryLoginFailureRepo(),
+		cfg: &config.Config{
+			LoginUserAttempts:  2,
+			LoginIPAttempts:    3,
+			LoginBackoffSec:    30,
+			LoginMaxLockoutSec: 300,
+			LoginWindowMin:     60,
+		},
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	throttle := newTestThrottle()
+	tests := []struct {
+		excess int
+		want   time.Duration
+	}{
+		{-1, 0},
+		{0, 0},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 5 * time.Minute},
+		{100, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := throttle.lockout(tt.excess); got != tt.want {
+			t.Errorf("lockout(%d) = %s, want %s", tt.excess, got, tt.want)
+		}
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	ctx := context.Background()
+	throttle := newTestThrottle()
+	now := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	keys := throttle.keys("alice", "192.0.2.1")
+
+	// The user key allows two failures; the third locks it out.
+	for i := 0; i < 3; i++ {
+		if _, err := throttle.reserve(ctx, keys, now); err != nil {
+			t.Fatalf("attempt %d refused: %v", i+1, err)
+		}
+	}
+	_, err := throttle.reserve(ctx, keys, now.Add(10*time.Second))
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfterSeconds() != 20 {
+		t.Fatalf("attempt while locked = %v, want a retry in 20s", err)
+	}
+
+	// A refused attempt leaves the address count alone, so another user
+	// from the same address still gets its last attempt.
+	other := throttle.keys("bob", "192.0.2.1")
+	reserved, err := throttle.reserve(ctx, other, now)
+	if err != nil {
+		t.Fatalf("other user refused: %v", err)
+	}
+	if got := reserved[1].Failures; got != 4 {
+		t.Errorf("address has %d failures, want 4", got)
+	}
+
+	// Succeeding clears the user's failures and takes back only the login
+	// itself from the address.
+	if err := throttle.succeed(ctx, other, reserved); err != nil {
+		t.Fatalf("succeed failed: %v", err)
+	}
+	reserved, err = throttle.reserve(ctx, other, now)
+	if err != nil {
+		t.Fatalf("login after success refused: %v", err)
+	}
+	if reserved[0].Failures != 1 || reserved[1].Failures != 4 {
+		t.Errorf("after success, user has %d failures and address %d; want 1 and 4",
+			reserved[0].Failures, reserved[1].Failures)
+	}
+
+	// Once the address is locked out, the user key that was reserved first
+	// is refunded.
+	if _, err := throttle.reserve(ctx, other, now); !errors.As(err, &throttled) {
+		t.Fatalf("attempt from a locked address = %v, want throttled", err)
+	}
+	reserved, err = throttle.reserve(ctx, throttle.keys("bob", ""), now)
+	if err != nil {
+		t.Fatalf("attempt without an address refused: %v", err)
+	}
+	if reserved[0].Failures != 2 {
+		t.Errorf("user has %d failures, want the refused attempt refunded", reserved[0].Failures)
+	}
+}
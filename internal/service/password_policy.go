@@ -0,0 +1,97 @@
+package service
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"tasklist/pkg/config"
+)
+
+// maxPasswordBytes is the length past which bcrypt ignores a password.
+const maxPasswordBytes = 72
+
+// usernamePattern admits usernames as normalizeUsername leaves them. Keeping
+// them to ASCII rules out names that merely look like another user's.
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,31}$`)
+
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+// commonPasswords is the bundled denylist, in lowercase.
+var commonPasswords = parseWordList(commonPasswordsList)
+
+func parseWordList(list string) map[string]struct{} {
+	words := make(map[string]struct{})
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words[line] = struct{}{}
+	}
+	return words
+}
+
+// normalizeUsername folds the spellings of a username that people would take
+// for the same name.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func validateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return ValidationError("username must be 3 to 32 letters, digits, '.', '_' or '-', starting with a letter or digit")
+	}
+	return nil
+}
+
+// checkPassword enforces the password policy of cfg on a new password for
+// the normalized username.
+func checkPassword(cfg *config.Config, username, password string) error {
+	if utf8.RuneCountInString(password) < cfg.PasswordMinLength {
+		return ValidationError(fmt.Sprintf("password must be at least %d characters", cfg.PasswordMinLength))
+	}
+	if len(password) > maxPasswordBytes {
+		return ValidationError(fmt.Sprintf("password must be at most %d bytes", maxPasswordBytes))
+	}
+	if characterClasses(password) < cfg.PasswordMinClasses {
+		return ValidationError(fmt.Sprintf("password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", cfg.PasswordMinClasses))
+	}
+	lower := strings.ToLower(password)
+	if strings.Contains(lower, username) {
+		return ValidationError("password must not contain the username")
+	}
+	if _, ok := commonPasswords[lower]; ok && cfg.PasswordDenylist {
+		return ValidationError("password is too common")
+	}
+	return nil
+}
+
+// characterClasses counts which of lowercase letters, uppercase letters,
+// digits and other characters password uses.
+func characterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	n := 0
+	for _, used := range []bool{lower, upper, digit, other} {
+		if used {
+			n++
+		}
+	}
+	return n
+}
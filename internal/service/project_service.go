@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"tasklist/internal/models"
+	"tasklist/internal/repository"
+)
+
+var (
+	ErrProjectNotFound = repository.ErrProjectNotFound
+	ErrProjectExists   = repository.ErrProjectExists
+)
+
+const maxProjectNameLength = 128
+
+type Project interface {
+	Create(ctx context.Context, userId int, name string) (*models.Project, error)
+	List(ctx context.Context, userId int) ([]models.Project, error)
+	GetByID(ctx context.Context, projectId, userId int) (*models.Project, error)
+	Update(ctx context.Context, projectId, userId int, name string) (*models.Project, error)
+	Delete(ctx context.Context, projectId, userId int, mode string) error
+}
+
+type ProjectService struct {
+	repo repository.Project
+}
+
+func NewProjectService(r repository.Project) *ProjectService {
+	return &ProjectService{repo: r}
+}
+
+func (s *ProjectService) Create(ctx context.Context, userId int, name string) (*models.Project, error) {
+	name, err := validateProjectName(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, userId, name)
+}
+
+func (s *ProjectService) List(ctx context.Context, userId int) ([]models.Project, error) {
+	return s.repo.List(ctx, userId)
+}
+
+func (s *ProjectService) GetByID(ctx context.Context, projectId, userId int) (*models.Project, error) {
+	return s.repo.GetByID(ctx, projectId, userId)
+}
+
+func (s *ProjectService) Update(ctx context.Context, projectId, userId int, name string) (*models.Project, error) {
+	name, err := validateProjectName(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, projectId, userId, name)
+}
+
+// Delete removes a project. Mode ProjectDeleteCascade deletes its tasks too;
+// the default, ProjectDeleteInbox, moves them to the inbox.
+func (s *ProjectService) Delete(ctx context.Context, projectId, userId int, mode string) error {
+	switch mode {
+	case "", models.ProjectDeleteInbox:
+		return s.repo.Delete(ctx, projectId, userId, false)
+	case models.ProjectDeleteCascade:
+		return s.repo.Delete(ctx, projectId, userId, true)
+	}
+	return ValidationError("mode must be cascade or inbox")
+}
+
+func validateProjectName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", ValidationError("project name is required")
+	}
+	if len(name) > maxProjectNameLength {
+		return "", ValidationError(fmt.Sprintf("project name must be at most %d bytes", maxProjectNameLength))
+	}
+	return name, nil
+}
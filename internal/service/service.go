@@ -1,18 +1,30 @@
 package service
 
 import (
+	"github.com/sirupsen/logrus"
+
 	"tasklist/internal/repository"
+	"tasklist/pkg/auth"
 	"tasklist/pkg/config"
 )
 
 type Service struct {
-	AuthService Auth
-	TaskService Task
+	AuthService    Auth
+	TaskService    Task
+	ProjectService Project
+	TagService     Tag
 }
 
-func NewService(repo *repository.Repository, cfg *config.Config) *Service {
+func NewService(repo *repository.Repository, logins repository.LoginFailure, keys *auth.Keyset, cfg *config.Config, log *logrus.Logger) *Service {
 	return &Service{
-		AuthService: NewAuthService(repo.UserRepo, cfg),
-		TaskService: NewTaskService(repo.TaskRepo),
+		AuthService:    NewAuthService(repo, logins, keys, cfg, log),
+		TaskService:    NewTaskService(repo),
+		ProjectService: NewProjectService(repo.ProjectRepo),
+		TagService:     NewTagService(repo.TagRepo),
 	}
 }
+
+// ValidationError reports input that a service rejected.
+type ValidationError string
+
+func (e ValidationError) Error() string { return string(e) }
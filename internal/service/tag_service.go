@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"tasklist/internal/models"
+	"tasklist/internal/repository"
+)
+
+var (
+	ErrTagNotFound = repository.ErrTagNotFound
+	ErrTagExists   = repository.ErrTagExists
+)
+
+// tagNamePattern keeps tag names usable as a single URL path segment.
+var tagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_.:-]{0,63}$`)
+
+type Tag interface {
+	Create(ctx context.Context, userId int, name string) (*models.Tag, error)
+	List(ctx context.Context, userId int) ([]models.Tag, error)
+	GetByID(ctx context.Context, tagId, userId int) (*models.Tag, error)
+	Update(ctx context.Context, tagId, userId int, name string) (*models.Tag, error)
+	Delete(ctx context.Context, tagId, userId int) error
+	AttachToTask(ctx context.Context, taskId, userId int, name string) error
+	DetachFromTask(ctx context.Context, taskId, userId int, name string) error
+}
+
+type TagService struct {
+	repo repository.Tag
+}
+
+func NewTagService(r repository.Tag) *TagService {
+	return &TagService{repo: r}
+}
+
+func (s *TagService) Create(ctx context.Context, userId int, name string) (*models.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, userId, name)
+}
+
+func (s *TagService) List(ctx context.Context, userId int) ([]models.Tag, error) {
+	return s.repo.List(ctx, userId)
+}
+
+func (s *TagService) GetByID(ctx context.Context, tagId, userId int) (*models.Tag, error) {
+	return s.repo.GetByID(ctx, tagId, userId)
+}
+
+func (s *TagService) Update(ctx context.Context, tagId, userId int, name string) (*models.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, tagId, userId, name); err != nil {
+		return nil, err
+	}
+	return &models.Tag{ID: tagId, Name: name}, nil
+}
+
+func (s *TagService) Delete(ctx context.Context, tagId, userId int) error {
+	return s.repo.Delete(ctx, tagId, userId)
+}
+
+func (s *TagService) AttachToTask(ctx context.Context, taskId, userId int, name string) error {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return err
+	}
+	return s.repo.Attach(ctx, taskId, userId, name)
+}
+
+func (s *TagService) DetachFromTask(ctx context.Context, taskId, userId int, name string) error {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return err
+	}
+	return s.repo.Detach(ctx, taskId, userId, name)
+}
+
+// normalizeTagName lower-cases name so that "Backend" and "backend" are the
+// same tag, and checks that it is a valid tag name.
+func normalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !tagNamePattern.MatchString(name) {
+		return "", ValidationError("tag name must be 1-64 letters, digits or _.:- and start with a letter or digit")
+	}
+	return name, nil
+}
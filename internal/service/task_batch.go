@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"tasklist/internal/models"
+)
+
+// ErrBatchAborted is the result of the operations of an atomic batch that
+// was rolled back because another operation failed.
+var ErrBatchAborted = errors.New("batch aborted by a failed operation")
+
+const maxBatchSize = 500
+
+// BatchResult is the outcome of one batch operation. Task is the task
+// created, updated or completed, and nil for deletions and failures.
+type BatchResult struct {
+	Task *models.Task
+	Err  error
+}
+
+// Batch runs the operations of req in order and returns a result for each.
+// Unless req.Partial, they share a transaction: when one fails nothing is
+// kept, the failed operation reports its error and the others
+// ErrBatchAborted.
+func (s *TaskService) Batch(ctx context.Context, userId int, req models.BatchRequest) ([]BatchResult, error) {
+	if len(req.Operations) == 0 {
+		return nil, ValidationError("operations must not be empty")
+	}
+	if len(req.Operations) > maxBatchSize {
+		return nil, ValidationError(fmt.Sprintf("a batch may have at most %d operations", maxBatchSize))
+	}
+
+	results := make([]BatchResult, len(req.Operations))
+	if req.Partial {
+		for i, op := range req.Operations {
+			err := s.withinTx(ctx, func(tx *TaskService) error {
+				task, err := tx.runBatchOperation(ctx, userId, op)
+				results[i].Task = task
+				return err
+			})
+			if err != nil {
+				results[i] = BatchResult{Err: err}
+			}
+		}
+		return results, nil
+	}
+
+	failed := -1
+	err := s.withinTx(ctx, func(tx *TaskService) error {
+		for i, op := range req.Operations {
+			task, err := tx.runBatchOperation(ctx, userId, op)
+			if err != nil {
+				failed = i
+				return err
+			}
+			results[i].Task = task
+		}
+		return nil
+	})
+	if err == nil {
+		return results, nil
+	}
+	if failed < 0 {
+		return nil, err
+	}
+	for i := range results {
+		results[i] = BatchResult{Err: ErrBatchAborted}
+	}
+	results[failed].Err = err
+	return results, nil
+}
+
+func (s *TaskService) runBatchOperation(ctx context.Context, userId int, op models.BatchOperation) (*models.Task, error) {
+	if op.Op != models.BatchCreate && op.ID == 0 {
+		return nil, ValidationError(op.Op + " needs a task id")
+	}
+	switch op.Op {
+	case models.BatchCreate, models.BatchUpdate:
+		if op.Task == nil {
+			return nil, ValidationError(op.Op + " needs a task")
+		}
+		if op.Op == models.BatchCreate {
+			return s.Create(ctx, userId, *op.Task)
+		}
+		return s.Update(ctx, op.ID, userId, op.Version, *op.Task)
+	case models.BatchComplete:
+		opts := models.CompleteOptions{Subtasks: op.Subtasks, Force: op.Force}
+		if err := s.Complete(ctx, op.ID, userId, opts); err != nil {
+			return nil, err
+		}
+		return s.repo.GetByID(ctx, op.ID, userId)
+	case models.BatchDelete:
+		return nil, s.Delete(ctx, op.ID, userId, op.Version)
+	default:
+		return nil, ValidationError(fmt.Sprintf("unknown operation %q", op.Op))
+	}
+}
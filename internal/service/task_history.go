@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+// History lists the events of a task, oldest first. Tasks in the trash keep
+// their history.
+func (s *TaskService) History(ctx context.Context, taskId, userId int) ([]models.TaskEvent, error) {
+	return s.events.History(ctx, taskId, userId)
+}
+
+// record stores an event of action, made by userId, on each of taskIds.
+func (s *TaskService) record(ctx context.Context, action models.TaskAction, userId int,
+	changes map[string]models.FieldChange, taskIds ...int) error {
+	if len(taskIds) == 0 {
+		return nil
+	}
+	if changes == nil {
+		changes = map[string]models.FieldChange{}
+	}
+	events := make([]models.TaskEvent, len(taskIds))
+	for i, id := range taskIds {
+		events[i] = models.TaskEvent{TaskID: id, Action: action, ActorID: &userId, Changes: changes}
+	}
+	return s.events.Record(ctx, events...)
+}
+
+// subtreeIDs returns the IDs of a task and its descendants.
+func subtreeIDs(taskId int, descendants []models.Task) []int {
+	ids := []int{taskId}
+	for _, task := range descendants {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func fieldChange(field string, old, new any) map[string]models.FieldChange {
+	return map[string]models.FieldChange{field: {Old: old, New: new}}
+}
+
+// requestChanges returns the editable fields that differ between old and new,
+// keyed by their JSON names.
+func requestChanges(old, new models.TaskRequest) map[string]models.FieldChange {
+	oldFields, newFields := requestFields(old), requestFields(new)
+	changes := make(map[string]models.FieldChange)
+	for field, value := range newFields {
+		if oldFields[field] != value {
+			changes[field] = models.FieldChange{Old: oldFields[field], New: value}
+		}
+	}
+	return changes
+}
+
+func requestFields(req models.TaskRequest) map[string]any {
+	return map[string]any{
+		"title":       req.Title,
+		"description": req.Description,
+		"priority":    req.Priority,
+		"deadline":    timeValue(req.Deadline),
+		"project_id":  intValue(req.ProjectID),
+		"parent_id":   intValue(req.ParentID),
+		"recurrence":  req.Recurrence,
+	}
+}
+
+// editableFields returns the fields of task that a TaskRequest sets.
+func editableFields(task *models.Task) models.TaskRequest {
+	return models.TaskRequest{
+		Title:       task.Title,
+		Description: task.Description,
+		Priority:    task.Priority,
+		Deadline:    task.Deadline,
+		ProjectID:   task.ProjectID,
+		ParentID:    task.ParentID,
+		Recurrence:  task.Recurrence,
+	}
+}
+
+// timeValue and intValue dereference optional fields into comparable values,
+// with times in UTC.
+func timeValue(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func intValue(n *int) any {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
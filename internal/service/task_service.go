@@ -1,53 +1,558 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"tasklist/internal/models"
 	"tasklist/internal/repository"
+	"tasklist/pkg/mergepatch"
+	"tasklist/pkg/recurrence"
+	"time"
 )
 
+var (
+	ErrTaskNotFound       = repository.ErrTaskNotFound
+	ErrInvalidCursor      = repository.ErrInvalidCursor
+	ErrDependencyNotFound = repository.ErrDependencyNotFound
+	ErrParentInTrash      = repository.ErrParentInTrash
+	ErrVersionMismatch    = repository.ErrVersionMismatch
+	ErrStatusChanged      = repository.ErrStatusChanged
+
+	ErrOpenSubtasks      = errors.New("task has open subtasks")
+	ErrTaskBlocked       = errors.New("task is blocked by open tasks")
+	ErrInvalidTransition = errors.New("cannot move task")
+	ErrResyncRequired    = errors.New("sync token is too old; fetch the full task list again")
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+
+	maxDescriptionLength = 10000
+
+	// maxSubtaskDepth is how many levels of subtasks a top-level task may have.
+	maxSubtaskDepth = 4
+)
+
+// statusTransitions lists the statuses each status may move to. Done and
+// cancelled tasks are reopened by moving them back to todo or in progress.
+var statusTransitions = map[models.TaskStatus][]models.TaskStatus{
+	models.StatusTodo:       {models.StatusInProgress, models.StatusBlocked, models.StatusDone, models.StatusCancelled},
+	models.StatusInProgress: {models.StatusTodo, models.StatusBlocked, models.StatusDone, models.StatusCancelled},
+	models.StatusBlocked:    {models.StatusTodo, models.StatusInProgress, models.StatusDone, models.StatusCancelled},
+	models.StatusDone:       {models.StatusTodo, models.StatusInProgress},
+	models.StatusCancelled:  {models.StatusTodo, models.StatusInProgress},
+}
+
+var taskSortFields = map[string]bool{
+	models.TaskSortID:       true,
+	models.TaskSortDeadline: true,
+	models.TaskSortTitle:    true,
+	models.TaskSortPriority: true,
+	models.TaskSortCreated:  true,
+	models.TaskSortUpdated:  true,
+}
+
 type Task interface {
-	Create(ctx context.Context, userId int, task models.TaskRequest) error
-	Complete(ctx context.Context, taskId int) error
-	List(ctx context.Context, userId int) ([]models.Task, error)
+	Create(ctx context.Context, userId int, task models.TaskRequest) (*models.Task, error)
+	Complete(ctx context.Context, taskId, userId int, opts models.CompleteOptions) error
+	Transition(ctx context.Context, taskId, userId int, req models.TransitionRequest) error
+	List(ctx context.Context, userId int, params models.TaskListParams) (*models.TaskList, error)
+	ListSubtasks(ctx context.Context, taskId, userId int, params models.TaskListParams) (*models.TaskList, error)
+	Search(ctx context.Context, userId int, query string, limit int) ([]models.Task, error)
 	GetByID(ctx context.Context, taskId, userId int) (*models.Task, error)
-	Update(ctx context.Context, taskId, userId int, task models.TaskRequest) error
-	Delete(ctx context.Context, id int) error
+	Update(ctx context.Context, taskId, userId, version int, task models.TaskRequest) (*models.Task, error)
+	Patch(ctx context.Context, taskId, userId, version int, patch []byte) (*models.Task, error)
+	Delete(ctx context.Context, taskId, userId, version int) error
+	ListTrash(ctx context.Context, userId int, params models.TaskListParams) (*models.TaskList, error)
+	Restore(ctx context.Context, taskId, userId int) error
+	Purge(ctx context.Context, taskId, userId int) error
+	EmptyTrash(ctx context.Context, userId int) (int, error)
+	AddDependency(ctx context.Context, taskId, blockerId, userId int) error
+	RemoveDependency(ctx context.Context, taskId, blockerId, userId int) error
+	History(ctx context.Context, taskId, userId int) ([]models.TaskEvent, error)
+	Batch(ctx context.Context, userId int, req models.BatchRequest) ([]BatchResult, error)
+	Changes(ctx context.Context, userId int, since string, limit int) (*models.TaskChanges, error)
+	LastModified(ctx context.Context, userId int) (time.Time, error)
 }
 type TaskService struct {
-	repo repository.Task
+	repos    *repository.Repository
+	repo     repository.Task
+	projects repository.Project
+	events   repository.TaskEvent
+}
+
+func NewTaskService(repos *repository.Repository) *TaskService {
+	return &TaskService{
+		repos:    repos,
+		repo:     repos.TaskRepo,
+		projects: repos.ProjectRepo,
+		events:   repos.EventRepo,
+	}
 }
 
-func NewTaskService(r repository.Task) *TaskService {
-	return &TaskService{repo: r}
+// withinTx calls fn with a service whose changes are committed together.
+func (s *TaskService) withinTx(ctx context.Context, fn func(tx *TaskService) error) error {
+	return s.repos.WithinTx(ctx, func(repos *repository.Repository) error {
+		return fn(NewTaskService(repos))
+	})
 }
 
-func (s *TaskService) Create(ctx context.Context, userId int, req models.TaskRequest) error {
-	if req.Title == "" {
-		return errors.New("title is required")
+// Create stores a new task and returns it.
+func (s *TaskService) Create(ctx context.Context, userId int, req models.TaskRequest) (*models.Task, error) {
+	var task *models.Task
+	err := s.withinTx(ctx, func(tx *TaskService) error {
+		if err := tx.validateTaskRequest(ctx, userId, 0, &req); err != nil {
+			return err
+		}
+		var err error
+		task, err = tx.create(ctx, userId, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	if err := s.repo.Create(ctx, userId, req); err != nil {
+	return task, nil
+}
+
+// create stores a validated task and records its creation. Like the other
+// lowercase mutations, it must run within a transaction.
+func (s *TaskService) create(ctx context.Context, userId int, req models.TaskRequest) (*models.Task, error) {
+	task, err := s.repo.Create(ctx, userId, req)
+	if err != nil {
+		return nil, err
+	}
+	changes := requestChanges(models.TaskRequest{}, req)
+	for field, change := range changes {
+		change.Old = nil
+		changes[field] = change
+	}
+	if err := s.record(ctx, models.TaskCreated, userId, changes, task.ID); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *TaskService) List(ctx context.Context, userId int, params models.TaskListParams) (*models.TaskList, error) {
+	if params.Limit == 0 {
+		params.Limit = defaultListLimit
+	}
+	if params.Limit < 0 || params.Limit > maxListLimit {
+		return nil, ValidationError(fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+	}
+	if params.Sort == "" {
+		params.Sort = "-" + models.TaskSortID
+	}
+	if field, _ := params.SortField(); !taskSortFields[field] {
+		return nil, ValidationError(fmt.Sprintf("unknown sort field %q", field))
+	}
+	for _, status := range params.Status {
+		if !status.Valid() {
+			return nil, ValidationError(fmt.Sprintf("unknown status %q", status))
+		}
+	}
+	switch params.TagMode {
+	case "", models.TagModeAll, models.TagModeAny:
+	default:
+		return nil, ValidationError("tag_mode must be all or any")
+	}
+	tags := make([]string, 0, len(params.Tags))
+	for _, tag := range params.Tags {
+		name, err := normalizeTagName(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(tags, name) {
+			tags = append(tags, name)
+		}
+	}
+	params.Tags = tags
+	return s.repo.List(ctx, userId, params)
+}
+func (s *TaskService) Search(ctx context.Context, userId int, query string, limit int) ([]models.Task, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ValidationError("search query is required")
+	}
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	if limit < 0 || limit > maxListLimit {
+		return nil, ValidationError(fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+	}
+	return s.repo.Search(ctx, userId, query, limit)
+}
+
+// Complete moves a task to done; see Transition.
+func (s *TaskService) Complete(ctx context.Context, taskId, userId int, opts models.CompleteOptions) error {
+	return s.Transition(ctx, taskId, userId, models.TransitionRequest{
+		Status:   models.StatusDone,
+		Subtasks: opts.Subtasks,
+		Force:    opts.Force,
+	})
+}
+
+// Transition moves a task to another status allowed by statusTransitions.
+// Closing a task with open subtasks fails with ErrOpenSubtasks, unless
+// SubtasksCascade closes them too. Unless forced, moving to done fails with
+// ErrTaskBlocked while a blocker is open. Completing a recurring task creates
+// its next occurrence, only the first time it is completed.
+func (s *TaskService) Transition(ctx context.Context, taskId, userId int, req models.TransitionRequest) error {
+	if !req.Status.Valid() {
+		return ValidationError("status must be one of todo, in_progress, blocked, done, cancelled")
+	}
+	cascade := false
+	switch req.Subtasks {
+	case "", models.SubtasksRequire:
+	case models.SubtasksCascade:
+		cascade = true
+	default:
+		return ValidationError("subtasks must be require or cascade")
+	}
+	return s.withinTx(ctx, func(tx *TaskService) error {
+		return tx.transition(ctx, taskId, userId, req, cascade)
+	})
+}
+
+// transition moves a task to the validated status of req, recording the
+// changes.
+func (s *TaskService) transition(ctx context.Context, taskId, userId int, req models.TransitionRequest, cascade bool) error {
+	task, err := s.repo.GetByID(ctx, taskId, userId)
+	if err != nil {
 		return err
 	}
-	return nil
+	if task.Status != req.Status && !slices.Contains(statusTransitions[task.Status], req.Status) {
+		return fmt.Errorf("%w from %s to %s", ErrInvalidTransition, task.Status, req.Status)
+	}
+	if req.Status == models.StatusDone && task.Status != models.StatusDone && task.IsBlocked && !req.Force {
+		return ErrTaskBlocked
+	}
+
+	var changed []models.Task
+	if task.Status != req.Status {
+		changed = append(changed, *task)
+	}
+	if req.Status.Closed() {
+		descendants, err := s.repo.Descendants(ctx, taskId, userId)
+		if err != nil {
+			return err
+		}
+		for _, task := range descendants {
+			if task.Status.Closed() {
+				continue
+			}
+			if !cascade {
+				return ErrOpenSubtasks
+			}
+			changed = append(changed, task)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	// Moving from the status just read makes a concurrent move fail with
+	// ErrStatusChanged instead of both going through.
+	if err := s.repo.SetStatus(ctx, taskId, userId, task.Status, req.Status, cascade && req.Status.Closed()); err != nil {
+		return err
+	}
+	events := make([]models.TaskEvent, len(changed))
+	for i, task := range changed {
+		events[i] = models.TaskEvent{
+			TaskID:  task.ID,
+			Action:  models.TaskStatusChanged,
+			ActorID: &userId,
+			Changes: fieldChange("status", task.Status, req.Status),
+		}
+	}
+	if err := s.events.Record(ctx, events...); err != nil {
+		return err
+	}
+	if req.Status != models.StatusDone || task.Status == models.StatusDone || task.Recurrence == "" ||
+		task.NextOccurrenceID != nil {
+		return nil
+	}
+	return s.createNextOccurrence(ctx, userId, task)
 }
 
-func (s *TaskService) List(ctx context.Context, userId int) ([]models.Task, error) {
-	return s.repo.List(ctx, userId)
+// createNextOccurrence copies a recurring task with its deadline moved to the
+// next occurrence still ahead, unless the series has ended, and links the
+// task to the copy.
+func (s *TaskService) createNextOccurrence(ctx context.Context, userId int, task *models.Task) error {
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil || task.Deadline == nil {
+		return fmt.Errorf("task %d has an invalid recurrence: %q", task.ID, task.Recurrence)
+	}
+	deadline, rest, ok := rule.Advance(*task.Deadline, time.Now())
+	if !ok {
+		return nil
+	}
+	next, err := s.create(ctx, userId, models.TaskRequest{
+		Title:       task.Title,
+		Description: task.Description,
+		Priority:    task.Priority,
+		Deadline:    &deadline,
+		ProjectID:   task.ProjectID,
+		ParentID:    task.ParentID,
+		Recurrence:  rest.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return s.repo.SetNextOccurrence(ctx, task.ID, userId, next.ID)
 }
-func (s *TaskService) Complete(ctx context.Context, taskId int) error {
-	return s.repo.Complete(ctx, taskId)
+
+// ListSubtasks lists the direct subtasks of a task.
+func (s *TaskService) ListSubtasks(ctx context.Context, taskId, userId int, params models.TaskListParams) (*models.TaskList, error) {
+	if _, err := s.repo.GetByID(ctx, taskId, userId); err != nil {
+		return nil, err
+	}
+	params.ParentID = &taskId
+	return s.List(ctx, userId, params)
 }
 
 func (s *TaskService) GetByID(ctx context.Context, taskId, userId int) (*models.Task, error) {
 	return s.repo.GetByID(ctx, taskId, userId)
 }
 
-func (s *TaskService) Update(ctx context.Context, taskId, userId int, task models.TaskRequest) error {
-	return s.repo.Update(ctx, taskId, userId, task)
+// Update replaces the editable fields of a task and returns the task. Unless
+// version is 0, it fails with ErrVersionMismatch once the task has changed
+// from that version, as do Patch and Delete.
+func (s *TaskService) Update(ctx context.Context, taskId, userId, version int, task models.TaskRequest) (*models.Task, error) {
+	if err := s.validateTaskRequest(ctx, userId, taskId, &task); err != nil {
+		return nil, err
+	}
+	var updated *models.Task
+	err := s.withinTx(ctx, func(tx *TaskService) error {
+		old, err := tx.repo.GetByID(ctx, taskId, userId)
+		if err != nil {
+			return err
+		}
+		updated, err = tx.update(ctx, userId, version, old, task)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Patch applies a JSON Merge Patch to the editable fields of a task and
+// returns the task. Fields missing from the patch keep their values and null
+// clears them, or resets them to their defaults.
+func (s *TaskService) Patch(ctx context.Context, taskId, userId, version int, patch []byte) (*models.Task, error) {
+	var updated *models.Task
+	err := s.withinTx(ctx, func(tx *TaskService) error {
+		var err error
+		updated, err = tx.patch(ctx, taskId, userId, version, patch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *TaskService) patch(ctx context.Context, taskId, userId, version int, patch []byte) (*models.Task, error) {
+	old, err := s.repo.GetByID(ctx, taskId, userId)
+	if err != nil {
+		return nil, err
+	}
+	current, err := json.Marshal(editableFields(old))
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergepatch.Apply(current, patch)
+	if err != nil {
+		return nil, ValidationError(err.Error())
+	}
+
+	var task models.TaskRequest
+	dec := json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&task); err != nil {
+		return nil, ValidationError("invalid task patch: " + err.Error())
+	}
+	if err := s.validateTaskRequest(ctx, userId, taskId, &task); err != nil {
+		return nil, err
+	}
+	return s.update(ctx, userId, version, old, task)
 }
 
-func (s *TaskService) Delete(ctx context.Context, id int) error {
-	return s.repo.Delete(ctx, id)
+// update stores the validated fields of task over those of old, records the
+// changes and returns the updated task.
+func (s *TaskService) update(ctx context.Context, userId, version int, old *models.Task, task models.TaskRequest) (*models.Task, error) {
+	if err := s.repo.Update(ctx, old.ID, userId, version, task); err != nil {
+		return nil, err
+	}
+	if changes := requestChanges(editableFields(old), task); len(changes) > 0 {
+		if err := s.record(ctx, models.TaskUpdated, userId, changes, old.ID); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetByID(ctx, old.ID, userId)
+}
+
+// Delete moves a task and its subtasks to the trash.
+func (s *TaskService) Delete(ctx context.Context, taskId, userId, version int) error {
+	return s.withinTx(ctx, func(tx *TaskService) error {
+		descendants, err := tx.repo.Descendants(ctx, taskId, userId)
+		if err != nil {
+			return err
+		}
+		if err := tx.repo.Delete(ctx, taskId, userId, version); err != nil {
+			return err
+		}
+		return tx.record(ctx, models.TaskDeleted, userId, nil, subtreeIDs(taskId, descendants)...)
+	})
+}
+
+// ListTrash lists deleted tasks, with the same filters as List.
+func (s *TaskService) ListTrash(ctx context.Context, userId int, params models.TaskListParams) (*models.TaskList, error) {
+	params.Trashed = true
+	return s.List(ctx, userId, params)
+}
+
+// Restore takes a task and the subtasks deleted along with it out of the
+// trash.
+func (s *TaskService) Restore(ctx context.Context, taskId, userId int) error {
+	return s.withinTx(ctx, func(tx *TaskService) error {
+		if err := tx.repo.Restore(ctx, taskId, userId); err != nil {
+			return err
+		}
+		// Subtasks of a task in the trash are all in the trash too, so the
+		// ones now live are the ones restored with it.
+		descendants, err := tx.repo.Descendants(ctx, taskId, userId)
+		if err != nil {
+			return err
+		}
+		return tx.record(ctx, models.TaskRestored, userId, nil, subtreeIDs(taskId, descendants)...)
+	})
+}
+
+// Purge permanently deletes a task in the trash.
+func (s *TaskService) Purge(ctx context.Context, taskId, userId int) error {
+	return s.repo.Purge(ctx, taskId, userId)
+}
+
+// EmptyTrash permanently deletes every task in the trash and returns how
+// many were deleted.
+func (s *TaskService) EmptyTrash(ctx context.Context, userId int) (int, error) {
+	return s.repo.EmptyTrash(ctx, userId)
+}
+
+// AddDependency marks taskId as blocked by blockerId, refusing dependencies
+// that would make a task wait on itself.
+func (s *TaskService) AddDependency(ctx context.Context, taskId, blockerId, userId int) error {
+	if _, err := s.repo.GetByID(ctx, taskId, userId); err != nil {
+		return err
+	}
+	if _, err := s.repo.GetByID(ctx, blockerId, userId); err != nil {
+		if errors.Is(err, ErrTaskNotFound) {
+			return ValidationError("blocking task not found")
+		}
+		return err
+	}
+	blockers, err := s.repo.Blockers(ctx, blockerId, userId)
+	if err != nil {
+		return err
+	}
+	if blockerId == taskId || slices.Contains(blockers, taskId) {
+		return ValidationError("dependency would create a cycle")
+	}
+	return s.repo.AddDependency(ctx, taskId, blockerId, userId)
+}
+
+func (s *TaskService) RemoveDependency(ctx context.Context, taskId, blockerId, userId int) error {
+	return s.repo.RemoveDependency(ctx, taskId, blockerId, userId)
+}
+
+// validateTaskRequest checks req and fills in defaults for omitted fields. A
+// project or parent task must belong to userId. taskId is the task being
+// updated, or 0 for a new one.
+func (s *TaskService) validateTaskRequest(ctx context.Context, userId, taskId int, req *models.TaskRequest) error {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		return ValidationError("title is required")
+	}
+	if len(req.Description) > maxDescriptionLength {
+		return ValidationError(fmt.Sprintf("description must be at most %d bytes", maxDescriptionLength))
+	}
+	if req.Priority == "" {
+		req.Priority = models.PriorityNormal
+	}
+	if !req.Priority.Valid() {
+		return ValidationError("priority must be one of low, normal, high, urgent")
+	}
+	if req.Recurrence != "" {
+		rule, err := recurrence.Parse(req.Recurrence)
+		if err != nil {
+			return ValidationError("invalid recurrence: " + err.Error())
+		}
+		if req.Deadline == nil {
+			return ValidationError("recurring tasks need a deadline")
+		}
+		req.Recurrence = rule.String()
+	}
+	if req.ProjectID != nil {
+		if _, err := s.projects.GetByID(ctx, *req.ProjectID, userId); err != nil {
+			return err
+		}
+	}
+	if req.ParentID != nil {
+		return s.validateParent(ctx, userId, taskId, *req.ParentID)
+	}
+	return nil
+}
+
+// validateParent checks that making parentId the parent of taskId neither
+// creates a cycle nor nests subtasks deeper than maxSubtaskDepth.
+func (s *TaskService) validateParent(ctx context.Context, userId, taskId, parentId int) error {
+	tooDeep := ValidationError(fmt.Sprintf("subtasks may be nested at most %d levels deep", maxSubtaskDepth))
+
+	// depth is the level the task would be at, counting its ancestors.
+	depth := 0
+	for id := &parentId; id != nil; {
+		if *id == taskId {
+			return ValidationError("a task cannot be a subtask of itself or of its own subtasks")
+		}
+		if depth++; depth > maxSubtaskDepth {
+			return tooDeep
+		}
+		parent, err := s.repo.GetByID(ctx, *id, userId)
+		if err != nil {
+			if errors.Is(err, ErrTaskNotFound) && *id == parentId {
+				return ValidationError("parent task not found")
+			}
+			return err
+		}
+		id = parent.ParentID
+	}
+	if taskId == 0 {
+		return nil
+	}
+
+	descendants, err := s.repo.Descendants(ctx, taskId, userId)
+	if err != nil {
+		return err
+	}
+	parents := make(map[int]int, len(descendants))
+	for _, task := range descendants {
+		parents[task.ID] = *task.ParentID
+	}
+	for _, task := range descendants {
+		level := depth
+		for id := task.ID; id != taskId; id = parents[id] {
+			level++
+		}
+		if level > maxSubtaskDepth {
+			return tooDeep
+		}
+	}
+	return nil
 }
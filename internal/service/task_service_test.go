@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"tasklist/internal/models"
+	"tasklist/internal/repository"
+)
+
+func newTestTaskService(t *testing.T) (*TaskService, *repository.Repository, int) {
+	t.Helper()
+	repos := repository.NewMemoryRepository()
+	userId, err := repos.UserRepo.Create(context.Background(), &models.User{Username: "alice", Password: "x"})
+	if err != nil {
+		t.Fatalf("creating user failed: %v", err)
+	}
+	return NewTaskService(repos), repos, userId
+}
+
+func TestCompleteRecurring(t *testing.T) {
+	ctx := context.Background()
+	done := models.TransitionRequest{Status: models.StatusDone}
+	reopen := models.TransitionRequest{Status: models.StatusTodo}
+	tests := []struct {
+		name  string
+		steps []models.TransitionRequest
+	}{
+		{"complete", []models.TransitionRequest{done}},
+		{"complete twice", []models.TransitionRequest{done, done}},
+		{"reopen and complete again", []models.TransitionRequest{done, reopen, done}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, userId := newTestTaskService(t)
+			deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+			task, err := s.Create(ctx, userId, models.TaskRequest{Title: "water plants", Deadline: &deadline, Recurrence: "daily"})
+			if err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			for _, step := range tt.steps {
+				if err := s.Transition(ctx, task.ID, userId, step); err != nil {
+					t.Fatalf("Transition to %s failed: %v", step.Status, err)
+				}
+			}
+			assertOneOccurrence(t, s, userId, deadline.AddDate(0, 0, 1))
+		})
+	}
+}
+
+func TestCompleteRecurringConcurrently(t *testing.T) {
+	ctx := context.Background()
+	s, _, userId := newTestTaskService(t)
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	task, err := s.Create(ctx, userId, models.TaskRequest{Title: "water plants", Deadline: &deadline, Recurrence: "daily"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.Complete(ctx, task.ID, userId, models.CompleteOptions{})
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, repository.ErrStatusChanged) {
+			t.Errorf("Complete failed: %v", err)
+		}
+	}
+	assertOneOccurrence(t, s, userId, deadline.AddDate(0, 0, 1))
+}
+
+// assertOneOccurrence checks that a recurring task was followed by exactly
+// one next occurrence, due at deadline.
+func assertOneOccurrence(t *testing.T, s *TaskService, userId int, deadline time.Time) {
+	t.Helper()
+	open := false
+	list, err := s.List(context.Background(), userId, models.TaskListParams{Completed: &open})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list.Tasks) != 1 {
+		t.Fatalf("%d open tasks follow the recurring task, want 1", len(list.Tasks))
+	}
+	if next := list.Tasks[0]; next.Deadline == nil || !next.Deadline.Equal(deadline) {
+		t.Errorf("next occurrence is due %v, want %s", next.Deadline, deadline)
+	}
+}
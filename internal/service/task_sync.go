@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+// Changes returns up to limit changes to tasks after the sync token since, or
+// from the start when it is empty. Tasks moved to the trash or purged from it
+// are reported as tombstones. The token numbers the last change reported; it
+// fails with ErrResyncRequired once tombstones it has not seen are dropped.
+func (s *TaskService) Changes(ctx context.Context, userId int, since string, limit int) (*models.TaskChanges, error) {
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	if limit < 0 || limit > maxListLimit {
+		return nil, ValidationError(fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+	}
+	var after int64
+	if since != "" {
+		var err error
+		if after, err = strconv.ParseInt(since, 10, 64); err != nil || after < 0 {
+			return nil, ValidationError("invalid sync token")
+		}
+	}
+	log, err := s.repo.Changes(ctx, userId, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	if after > log.Latest {
+		return nil, ValidationError("invalid sync token")
+	}
+	if after > 0 && after < log.Horizon {
+		return nil, ErrResyncRequired
+	}
+
+	changes := &models.TaskChanges{
+		Tasks:     []models.Task{},
+		Deleted:   []models.TaskTombstone{},
+		SyncToken: strconv.FormatInt(log.Latest, 10),
+		HasMore:   len(log.Changes) > limit,
+	}
+	if changes.HasMore {
+		log.Changes = log.Changes[:limit]
+		changes.SyncToken = strconv.FormatInt(log.Changes[limit-1].Seq, 10)
+	}
+	for _, change := range log.Changes {
+		switch {
+		case change.Purged != nil:
+			changes.Deleted = append(changes.Deleted, *change.Purged)
+		case change.Task.DeletedAt != nil:
+			changes.Deleted = append(changes.Deleted, models.TaskTombstone{ID: change.Task.ID, DeletedAt: *change.Task.DeletedAt})
+		default:
+			changes.Tasks = append(changes.Tasks, *change.Task)
+		}
+	}
+	return changes, nil
+}
+
+// LastModified returns when a task of userId last changed, including moves to
+// the trash, or the zero time if there are none.
+func (s *TaskService) LastModified(ctx context.Context, userId int) (time.Time, error) {
+	list, err := s.repo.List(ctx, userId, models.TaskListParams{
+		Limit:       1,
+		Sort:        "-" + models.TaskSortUpdated,
+		WithTrashed: true,
+	})
+	if err != nil || len(list.Tasks) == 0 {
+		return time.Time{}, err
+	}
+	return list.Tasks[0].UpdatedAt, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tasklist/internal/models"
+)
+
+func TestChanges(t *testing.T) {
+	ctx := context.Background()
+	s, repos, userId := newTestTaskService(t)
+	var ids []int
+	for _, title := range []string{"a", "b", "c"} {
+		task, err := s.Create(ctx, userId, models.TaskRequest{Title: title})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	first, err := s.Changes(ctx, userId, "", 2)
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(first.Tasks) != 2 || !first.HasMore || first.SyncToken != "2" {
+		t.Fatalf("first page = %d tasks, more %v, token %q; want 2, true, 2",
+			len(first.Tasks), first.HasMore, first.SyncToken)
+	}
+
+	if err := s.Delete(ctx, ids[0], userId, 0); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := s.Delete(ctx, ids[1], userId, 0); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := s.Purge(ctx, ids[1], userId); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	rest, err := s.Changes(ctx, userId, first.SyncToken, 10)
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(rest.Tasks) != 1 || rest.Tasks[0].ID != ids[2] || len(rest.Deleted) != 2 || rest.HasMore {
+		t.Fatalf("second page = %+v, want task %d and two tombstones", rest, ids[2])
+	}
+
+	if _, err := repos.TaskRepo.PurgeTombstones(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PurgeTombstones failed: %v", err)
+	}
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"from the start", "", nil},
+		{"after the dropped tombstone", rest.SyncToken, nil},
+		{"before the dropped tombstone", first.SyncToken, ErrResyncRequired},
+		{"not a number", "abc", ValidationError("invalid sync token")},
+		{"negative", "-1", ValidationError("invalid sync token")},
+		{"ahead of the changes", "100", ValidationError("invalid sync token")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Changes(ctx, userId, tt.token, 10); !errors.Is(err, tt.want) {
+				t.Errorf("Changes(%q) = %v, want %v", tt.token, err, tt.want)
+			}
+		})
+	}
+}
@@ -13,8 +13,10 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"tasklist/internal/handler"
+	"tasklist/internal/models"
 	"tasklist/internal/repository"
 	"tasklist/internal/service"
+	"tasklist/pkg/auth"
 	"tasklist/pkg/config"
 	"tasklist/pkg/logger"
 )
@@ -36,20 +38,41 @@ func main() {
 		panic(err)
 	}
 	log := logger.New(cfg.LogLevel)
-	log.Info("Starting server...")
 
-	db, err := db.InitDB(cfg.DatabaseURL)
+	repo, migrator, closeRepo, err := initRepository(cfg, log)
 	if err != nil {
 		panic(err)
 	}
-	defer db.Pool.Close()
+	defer closeRepo()
 
-	log.Info("Connected to database...")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), migrator, os.Args[2:], log); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if err := prepareSchema(context.Background(), migrator, cfg.AutoMigrate, log); err != nil {
+		log.Fatalf("database schema: %v", err)
+	}
+	log.Info("Starting server...")
 
-	repo := repository.NewRepository(db)
-	service := service.NewService(repo, cfg)
-	handler := handler.NewHandler(service, log)
+	keys, err := auth.NewKeyset(cfg)
+	if err != nil {
+		log.Fatalf("jwt keys: %v", err)
+	}
+	logins := repo.LoginRepo
+	if cfg.LoginThrottleStore == config.ThrottleStoreMemory {
+		// Failed logins are then counted by each node on its own.
+		logins = repository.NewMemoryLoginFailureRepo()
+	}
+	service := service.NewService(repo, logins, keys, cfg, log)
+	handler := handler.NewHandler(service, keys, log)
 	r := handler.Init()
+	// Without trusted proxies, the client address of a request is that of
+	// its connection, and X-Forwarded-For cannot dodge login throttling.
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("trusted proxies: %v", err)
+	}
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
@@ -59,7 +82,15 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	go startOverdueChecker(ctx, repo.TaskRepo, log)
+	go startOverdueChecker(ctx, repo, log)
+	if cfg.TrashRetentionDays > 0 {
+		go startTrashPurger(ctx, repo.TaskRepo, time.Duration(cfg.TrashRetentionDays)*24*time.Hour, log)
+	}
+	if cfg.TombstoneRetentionDays > 0 {
+		go startTombstonePurger(ctx, repo.TaskRepo, time.Duration(cfg.TombstoneRetentionDays)*24*time.Hour, log)
+	}
+	go startTokenPurger(ctx, repo.TokenRepo, log)
+	go startLoginFailurePurger(ctx, logins, time.Duration(cfg.LoginWindowMin)*time.Minute, log)
 
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -79,40 +110,213 @@ func main() {
 	log.Info("server exiting")
 }
 
-func startOverdueChecker(ctx context.Context, repo repository.Task, log *logrus.Logger) {
-	ticker := time.NewTicker(time.Hour * 1)
-	defer ticker.Stop()
+// initRepository opens the configured storage backend. The returned migrator
+// is nil for the in-memory backend, which has no schema.
+func initRepository(cfg *config.Config, log *logrus.Logger) (*repository.Repository, *db.Migrator, func(), error) {
+	switch cfg.StorageBackend {
+	case config.StorageMemory:
+		log.Info("Using in-memory storage...")
+		return repository.NewMemoryRepository(), nil, func() {}, nil
+	case config.StorageSQLite:
+		database, err := db.InitSQLite(cfg.SQLitePath)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		migrator, err := db.NewMigrator(database.DB, db.DialectSQLite)
+		if err != nil {
+			database.DB.Close()
+			return nil, nil, nil, err
+		}
+		log.Infof("Using SQLite database %s...", cfg.SQLitePath)
+		return repository.NewSQLiteRepository(database), migrator, func() { database.DB.Close() }, nil
+	default:
+		database, err := db.InitDB(cfg.DatabaseURL)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		sqlDB := database.SQL()
+		migrator, err := db.NewMigrator(sqlDB, db.DialectPostgres)
+		if err != nil {
+			database.Pool.Close()
+			return nil, nil, nil, err
+		}
+		log.Info("Connected to database...")
+		closeFn := func() {
+			sqlDB.Close()
+			database.Pool.Close()
+		}
+		return repository.NewRepository(database), migrator, closeFn, nil
+	}
+}
 
-	checkOnce := func() {
-		log.Info("checking overdue tasks...")
-		tasks, err := repo.ListAll(ctx)
+// prepareSchema applies pending migrations when autoMigrate is set and
+// refuses to continue if the database schema is newer than this binary.
+func prepareSchema(ctx context.Context, migrator *db.Migrator, autoMigrate bool, log *logrus.Logger) error {
+	if migrator == nil {
+		return nil
+	}
+	if autoMigrate {
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Infof("applied migration %03d_%s", m.Version, m.Name)
+		}
+		return err
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		log.Warnf("%d pending migrations; run \"migrate up\" to apply them", pending)
+	}
+	return nil
+}
+
+// runMigrate implements the "migrate up|down|status" subcommand.
+func runMigrate(ctx context.Context, migrator *db.Migrator, args []string, log *logrus.Logger) error {
+	if migrator == nil {
+		return fmt.Errorf("storage backend has no migrations")
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: migrate up|down|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Infof("applied migration %03d_%s", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Info("database is up to date")
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx)
 		if err != nil {
-			log.WithError(err).Error("list tasks failed")
-			return
+			return err
+		}
+		if reverted == nil {
+			log.Info("no migrations to revert")
+			return nil
+		}
+		log.Infof("reverted migration %03d_%s", reverted.Version, reverted.Name)
+		return nil
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%03d_%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+// startOverdueChecker marks tasks past their deadline overdue, recording the
+// change in their history in the same transaction.
+func startOverdueChecker(ctx context.Context, repo *repository.Repository, log *logrus.Logger) {
+	runHourly(ctx, "overdue checker", func() (int, error) {
+		tasks, err := repo.TaskRepo.ListAll(ctx)
+		if err != nil {
+			return 0, err
 		}
 		now := time.Now()
+		overdue := 0
 		for _, task := range tasks {
-			if task.Deadline != nil && task.Deadline.Before(now) {
+			if task.Deadline == nil || !task.Deadline.Before(now) {
+				continue
+			}
+			var marked bool
+			err := repo.WithinTx(ctx, func(tx *repository.Repository) error {
+				// The task may have been closed or trashed since it was listed.
+				var err error
+				if marked, err = tx.TaskRepo.MarkOverdued(ctx, task.ID); err != nil || !marked {
+					return err
+				}
+				return tx.EventRepo.Record(ctx, models.TaskEvent{
+					TaskID:  task.ID,
+					Action:  models.TaskOverdue,
+					Changes: map[string]models.FieldChange{"is_overdue": {Old: false, New: true}},
+				})
+			})
+			if err != nil {
+				log.WithError(err).WithField("id", task.ID).Error("mark overdue failed")
+				continue
+			}
+			if marked {
+				overdue++
 				log.WithFields(logrus.Fields{
 					"id":       task.ID,
 					"deadline": task.Deadline.Format(time.RFC3339),
 				}).Info("task is overdue")
-				if err := repo.MarkOverdued(ctx, task.ID); err != nil {
-					log.WithError(err).WithField("id", task.ID).Error("mark overdue failed")
-				}
 			}
 		}
-		log.Info("overdue check complete")
+		return overdue, nil
+	}, log)
+}
+
+// startTrashPurger permanently deletes tasks that have been in the trash for
+// longer than retention.
+func startTrashPurger(ctx context.Context, repo repository.Task, retention time.Duration, log *logrus.Logger) {
+	runHourly(ctx, "trash purger", func() (int, error) {
+		return repo.PurgeDeleted(ctx, time.Now().Add(-retention))
+	}, log)
+}
+
+// startTombstonePurger forgets tasks purged longer than retention ago.
+// Clients that have not synced since are told to fetch the full list again.
+func startTombstonePurger(ctx context.Context, repo repository.Task, retention time.Duration, log *logrus.Logger) {
+	runHourly(ctx, "tombstone purger", func() (int, error) {
+		return repo.PurgeTombstones(ctx, time.Now().Add(-retention))
+	}, log)
+}
+
+// startTokenPurger deletes expired refresh tokens, which can no longer be
+// used or reused.
+func startTokenPurger(ctx context.Context, repo repository.RefreshToken, log *logrus.Logger) {
+	runHourly(ctx, "refresh token purger", func() (int, error) {
+		return repo.PurgeExpired(ctx, time.Now())
+	}, log)
+}
+
+// startLoginFailurePurger deletes the failed logins that are older than the
+// failure window, and no longer count.
+func startLoginFailurePurger(ctx context.Context, repo repository.LoginFailure, window time.Duration, log *logrus.Logger) {
+	runHourly(ctx, "login failure purger", func() (int, error) {
+		return repo.Purge(ctx, time.Now().Add(-window))
+	}, log)
+}
+
+// runHourly calls fn now and then every hour until ctx is done, logging what
+// the job named name failed or how many records it changed.
+func runHourly(ctx context.Context, name string, fn func() (int, error), log *logrus.Logger) {
+	ticker := time.NewTicker(time.Hour * 1)
+	defer ticker.Stop()
+
+	runOnce := func() {
+		n, err := fn()
+		if err != nil {
+			log.WithError(err).Error(name + " failed")
+			return
+		}
+		if n > 0 {
+			log.WithField("count", n).Info(name + " ran")
+		}
 	}
 
-	checkOnce()
+	runOnce()
 	for {
 		select {
 		case <-ctx.Done():
-			log.Info("overdue checker stopping")
+			log.Info(name + " stopping")
 			return
 		case <-ticker.C:
-			checkOnce()
+			runOnce()
 		}
 	}
 }
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"tasklist/pkg/config"
+)
+
+func TestJWKS(t *testing.T) {
+	edPublic, edKey := newEd25519Key(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := newKeyset(t, testConfig()).JWKS().Keys; len(keys) != 0 {
+		t.Errorf("HS256 keyset publishes %+v", keys)
+	}
+
+	cfg := testConfig()
+	cfg.JwtAlgorithm, cfg.JwtPrivateKeyFile = config.JwtEdDSA, edKey
+	cfg.JwtOldSecrets = map[string]string{"old": "old-secret"}
+	cfg.JwtPublicKeyFiles = map[string]string{"a-rsa": writePEM(t, "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))}
+	keys := newKeyset(t, cfg).JWKS().Keys
+
+	edKid, err := thumbprint(edPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].Kid > keys[1].Kid {
+		t.Fatalf("JWKS = %+v, want the RSA and Ed25519 keys ordered by key ID", keys)
+	}
+	byKid := map[string]JWK{keys[0].Kid: keys[0], keys[1].Kid: keys[1]}
+	if k := byKid["a-rsa"]; k.Kty != "RSA" || k.Alg != "RS256" || k.Use != "sig" || k.E != "AQAB" {
+		t.Errorf("RSA key = %+v", k)
+	}
+	if k := byKid[edKid]; k.Kty != "OKP" || k.Crv != "Ed25519" || k.Alg != "EdDSA" || k.Use != "sig" ||
+		k.X != base64.RawURLEncoding.EncodeToString(edPublic) {
+		t.Errorf("Ed25519 key = %+v, want it named by its thumbprint %s", k, edKid)
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	// The example of RFC 7638, section 3.1.
+	n := "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+	modulus, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := thumbprint(&rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: 65537})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("thumbprint = %s, want %s", got, want)
+	}
+}
@@ -1,48 +1,102 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
-	"tasklist/internal/models"
-	"tasklist/pkg/config"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// clockSkew is how far the clocks of the issuer and of this server may
+// disagree when checking the expiry and not-before times of a token.
+const clockSkew = 30 * time.Second
+
+// Claims are the claims of an access token. SessionID names the login
+// session, which may be revoked before the token expires.
 type Claims struct {
-	UserID int `json:"user_id"`
+	UserID    int    `json:"user_id"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
-type TokenData struct {
-	UserID int `json:"user_id"`
-}
 
-func GenerateToken(userID int, cfg *config.Config) (string, error) {
+// GenerateToken issues an access token for a session of userID, signed with
+// the signing key of k and naming it in the kid header.
+func (k *Keyset) GenerateToken(userID int, sessionID string) (string, error) {
+	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.JwtTtlMin) * time.Minute)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    k.issuer,
+			Subject:   strconv.Itoa(userID),
+			Audience:  jwt.ClaimStrings{k.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(k.ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.JwtSecret))
-}
-
-func ParseToken(tokenStr string) (*TokenData, error) {
-	var claims TokenData
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte("secret"), nil
-	})
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	if k.signing.id != "" {
+		token.Header["kid"] = k.signing.id
+	}
+	return token.SignedString(k.signing.sign)
+}
+
+// ParseToken verifies an access token against the key named by its kid
+// header, and checks its issuer, audience, expiry and not-before times.
+func (k *Keyset) ParseToken(tokenStr string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(tokenStr, &claims, k.verificationKey,
+		jwt.WithIssuer(k.issuer),
+		jwt.WithAudience(k.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+	)
 	if err != nil {
 		return nil, err
 	}
-	if tokenClaims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		claims.UserID = int(tokenClaims[models.UserCtxKey].(float64))
-		return &claims, nil
+	return &claims, nil
+}
+
+func (k *Keyset) verificationKey(token *jwt.Token) (any, error) {
+	id, _ := token.Header["kid"].(string)
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", id)
+	}
+	// A key only verifies tokens of its own algorithm, so that a public key
+	// is never taken for an HMAC secret.
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verify, nil
+}
+
+// NewSessionID returns a random ID for a login session.
+func NewSessionID() (string, error) {
+	return randomString(16)
+}
+
+// NewRefreshToken returns a random refresh token. Only its hash, from
+// HashRefreshToken, is stored.
+func NewRefreshToken() (string, error) {
+	return randomString(32)
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	return nil, jwt.ErrTokenInvalidClaims
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
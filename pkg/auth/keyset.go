@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"tasklist/pkg/config"
+)
+
+// Keyset holds the key that signs access tokens and every key that tokens
+// may still be verified with, by key ID. Keeping retired keys lets secrets be
+// rotated without invalidating the tokens already issued.
+type Keyset struct {
+	signing  *key
+	keys     map[string]*key
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+type key struct {
+	id     string
+	method jwt.SigningMethod
+	// sign is nil for retired keys, which only verify.
+	sign   any
+	verify any
+}
+
+// NewKeyset builds the keyset of a configuration: the signing key named
+// JwtKeyID, either the HMAC secret JwtSecret or the private key in
+// JwtPrivateKeyFile, the retired HMAC secrets in JwtOldSecrets and the
+// public keys in JwtPublicKeyFiles. JWT_OLD_SECRETS and JWT_PUBLIC_KEY_FILES
+// list them by key ID, as kid1:value1,kid2:value2, so neither IDs nor values
+// may contain "," or ":"; other secrets go in JWT_OLD_SECRET_<kid>. A private
+// key without a configured ID is named by its RFC 7638 thumbprint.
+func NewKeyset(cfg *config.Config) (*Keyset, error) {
+	signing, err := signingKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ks := &Keyset{
+		signing:  signing,
+		keys:     map[string]*key{signing.id: signing},
+		issuer:   cfg.JwtIssuer,
+		audience: cfg.JwtAudience,
+		ttl:      time.Duration(cfg.JwtTtlMin) * time.Minute,
+	}
+	for id, secret := range cfg.JwtOldSecrets {
+		if err := ks.add(&key{id: id, method: jwt.SigningMethodHS256, verify: []byte(secret)}); err != nil {
+			return nil, err
+		}
+	}
+	for id, path := range cfg.JwtPublicKeyFiles {
+		public, err := loadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		method, err := publicKeyMethod(public)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := ks.add(&key{id: id, method: method, verify: public}); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+func (k *Keyset) add(key *key) error {
+	if _, ok := k.keys[key.id]; ok {
+		return fmt.Errorf("duplicate JWT key ID %q", key.id)
+	}
+	k.keys[key.id] = key
+	return nil
+}
+
+func signingKey(cfg *config.Config) (*key, error) {
+	if cfg.JwtAlgorithm == config.JwtHS256 {
+		secret := []byte(cfg.JwtSecret)
+		return &key{id: cfg.JwtKeyID, method: jwt.SigningMethodHS256, sign: secret, verify: secret}, nil
+	}
+
+	private, err := loadPrivateKey(cfg.JwtPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s holds an unsupported private key", cfg.JwtPrivateKeyFile)
+	}
+	public := signer.Public()
+	method, err := publicKeyMethod(public)
+	if err != nil || method.Alg() != cfg.JwtAlgorithm {
+		return nil, fmt.Errorf("%s does not hold a private key for %s", cfg.JwtPrivateKeyFile, cfg.JwtAlgorithm)
+	}
+	id := cfg.JwtKeyID
+	if id == "" {
+		if id, err = thumbprint(public); err != nil {
+			return nil, err
+		}
+	}
+	return &key{id: id, method: method, sign: private, verify: public}, nil
+}
+
+// publicKeyMethod returns the signing method that a public key verifies.
+func publicKeyMethod(public any) (jwt.SigningMethod, error) {
+	switch public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", public)
+}
+
+// loadPrivateKey reads a PEM-encoded PKCS #8 private key, or a PKCS #1 RSA
+// private key.
+func loadPrivateKey(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s holds no PEM data", path)
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("%s holds a %s, not a private key", path, block.Type)
+}
+
+// loadPublicKey reads a PEM-encoded PKIX or PKCS #1 RSA public key, or takes
+// the public half of a private key.
+func loadPublicKey(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s holds no PEM data", path)
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	private, err := loadPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s holds an unsupported private key", path)
+	}
+	return signer.Public(), nil
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set, as served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that tokens may be verified with, ordered by
+// key ID. HMAC secrets are never published, so a keyset signing with HS256
+// has none.
+func (k *Keyset) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.keys {
+		jwk, ok := publicJWK(key.verify)
+		if !ok {
+			continue
+		}
+		jwk.Kid = key.id
+		jwk.Use = "sig"
+		jwk.Alg = key.method.Alg()
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// publicJWK holds the key material of a public key, without its metadata.
+func publicJWK(public any) (JWK, bool) {
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(public)}, true
+	}
+	return JWK{}, false
+}
+
+// thumbprint returns the RFC 7638 thumbprint of a public key: the SHA-256
+// hash of its required members, serialized in lexicographic order.
+func thumbprint(public any) (string, error) {
+	jwk, ok := publicJWK(public)
+	if !ok {
+		return "", fmt.Errorf("unsupported key type %T", public)
+	}
+	// Marshalling a map sorts its keys.
+	members := map[string]string{"kty": jwk.Kty}
+	if jwk.Kty == "RSA" {
+		members["n"], members["e"] = jwk.N, jwk.E
+	} else {
+		members["crv"], members["x"] = jwk.Crv, jwk.X
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"tasklist/pkg/config"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		JwtAlgorithm: config.JwtHS256,
+		JwtSecret:    "current-secret",
+		JwtIssuer:    "tasklist",
+		JwtAudience:  "tasklist",
+		JwtTtlMin:    60,
+	}
+}
+
+// writePEM stores a key in a PEM file of a temporary directory.
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newEd25519Key(t *testing.T) (ed25519.PublicKey, string) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return public, writePEM(t, "PRIVATE KEY", der)
+}
+
+func newKeyset(t *testing.T, cfg *config.Config) *Keyset {
+	t.Helper()
+	ks, err := NewKeyset(cfg)
+	if err != nil {
+		t.Fatalf("NewKeyset failed: %v", err)
+	}
+	return ks
+}
+
+// signedToken signs valid claims for user 1 with method and key, naming kid.
+func signedToken(t *testing.T, method jwt.SigningMethod, kid string, key any) string {
+	t.Helper()
+	now := time.Now()
+	token := jwt.NewWithClaims(method, &Claims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "tasklist",
+			Audience:  jwt.ClaimStrings{"tasklist"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRoundTrip(t *testing.T) {
+	_, edKey := newEd25519Key(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPath := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	tests := []struct {
+		name string
+		cfg  func(cfg *config.Config)
+	}{
+		{"HS256 without kid", func(cfg *config.Config) {}},
+		{"HS256 with kid", func(cfg *config.Config) { cfg.JwtKeyID = "k1" }},
+		{"EdDSA", func(cfg *config.Config) { cfg.JwtAlgorithm, cfg.JwtPrivateKeyFile = config.JwtEdDSA, edKey }},
+		{"RS256", func(cfg *config.Config) { cfg.JwtAlgorithm, cfg.JwtPrivateKeyFile = config.JwtRS256, rsaPath }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tt.cfg(cfg)
+			ks := newKeyset(t, cfg)
+			token, err := ks.GenerateToken(7, "session")
+			if err != nil {
+				t.Fatalf("GenerateToken failed: %v", err)
+			}
+			claims, err := ks.ParseToken(token)
+			if err != nil {
+				t.Fatalf("ParseToken failed: %v", err)
+			}
+			if claims.UserID != 7 || claims.SessionID != "session" {
+				t.Errorf("ParseToken = user %d, session %q", claims.UserID, claims.SessionID)
+			}
+		})
+	}
+}
+
+func TestParseTokenKeys(t *testing.T) {
+	edPublic, edKey := newEd25519Key(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.JwtAlgorithm, cfg.JwtPrivateKeyFile, cfg.JwtKeyID = config.JwtEdDSA, edKey, "ed"
+	cfg.JwtOldSecrets = map[string]string{"old": "old-secret"}
+	cfg.JwtPublicKeyFiles = map[string]string{"rsa": writePEM(t, "PUBLIC KEY", rsaDER)}
+	ks := newKeyset(t, cfg)
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"retired secret", signedToken(t, jwt.SigningMethodHS256, "old", []byte("old-secret")), true},
+		{"verify-only public key", signedToken(t, jwt.SigningMethodRS256, "rsa", rsaKey), true},
+		{"wrong secret", signedToken(t, jwt.SigningMethodHS256, "old", []byte("guessed")), false},
+		{"unknown kid", signedToken(t, jwt.SigningMethodHS256, "new", []byte("old-secret")), false},
+		{"missing kid", signedToken(t, jwt.SigningMethodHS256, "", []byte("old-secret")), false},
+		// A public key must not pass for an HMAC secret.
+		{"HS256 with Ed25519 public key", signedToken(t, jwt.SigningMethodHS256, "ed", []byte(edPublic)), false},
+		{"HS256 with RSA public key", signedToken(t, jwt.SigningMethodHS256, "rsa", rsaDER), false},
+		{"RS256 under HMAC kid", signedToken(t, jwt.SigningMethodRS256, "old", rsaKey), false},
+		{"none", signedToken(t, jwt.SigningMethodNone, "old", jwt.UnsafeAllowNoneSignatureType), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ks.ParseToken(tt.token)
+			if (err == nil) != tt.ok {
+				t.Errorf("ParseToken error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseTokenClaims(t *testing.T) {
+	ks := newKeyset(t, testConfig())
+	secret := []byte("current-secret")
+	now := time.Now()
+	tests := []struct {
+		name   string
+		claims jwt.RegisteredClaims
+		ok     bool
+	}{
+		{"valid", jwt.RegisteredClaims{Issuer: "tasklist", Audience: jwt.ClaimStrings{"tasklist"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute))}, true},
+		{"within clock skew", jwt.RegisteredClaims{Issuer: "tasklist", Audience: jwt.ClaimStrings{"tasklist"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(-10 * time.Second))}, true},
+		{"expired", jwt.RegisteredClaims{Issuer: "tasklist", Audience: jwt.ClaimStrings{"tasklist"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(-time.Minute))}, false},
+		{"no expiry", jwt.RegisteredClaims{Issuer: "tasklist", Audience: jwt.ClaimStrings{"tasklist"}}, false},
+		{"other issuer", jwt.RegisteredClaims{Issuer: "other", Audience: jwt.ClaimStrings{"tasklist"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute))}, false},
+		{"other audience", jwt.RegisteredClaims{Issuer: "tasklist", Audience: jwt.ClaimStrings{"other"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute))}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: 1, RegisteredClaims: tt.claims}).SignedString(secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ks.ParseToken(token); (err == nil) != tt.ok {
+				t.Errorf("ParseToken error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewKeysetErrors(t *testing.T) {
+	_, edKey := newEd25519Key(t)
+	tests := []struct {
+		name string
+		cfg  func(cfg *config.Config)
+		want string
+	}{
+		{"key of another algorithm", func(cfg *config.Config) {
+			cfg.JwtAlgorithm, cfg.JwtPrivateKeyFile = config.JwtRS256, edKey
+		}, "does not hold a private key for RS256"},
+		{"missing key file", func(cfg *config.Config) {
+			cfg.JwtAlgorithm, cfg.JwtPrivateKeyFile = config.JwtEdDSA, filepath.Join(t.TempDir(), "none.pem")
+		}, "no such file"},
+		{"duplicate kid", func(cfg *config.Config) {
+			cfg.JwtKeyID, cfg.JwtOldSecrets = "k1", map[string]string{"k1": "old-secret"}
+		}, "duplicate JWT key ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tt.cfg(cfg)
+			if _, err := NewKeyset(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewKeyset error = %v, want one about %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestLoadOldSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		listed  map[string]string
+		environ []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"none", nil, []string{"JWT_SECRET=s"}, nil, false},
+		{"listed only", map[string]string{"a": "x"}, nil, map[string]string{"a": "x"}, false},
+		{"secret with separators", nil, []string{"JWT_OLD_SECRET_b=p:q,r=s"}, map[string]string{"b": "p:q,r=s"}, false},
+		{"both kinds", map[string]string{"a": "x"}, []string{"JWT_OLD_SECRET_b=y"}, map[string]string{"a": "x", "b": "y"}, false},
+		{"key in both", map[string]string{"a": "x"}, []string{"JWT_OLD_SECRET_a=y"}, nil, true},
+		{"no key ID", nil, []string{"JWT_OLD_SECRET_=y"}, nil, true},
+	}
+	for _, tt := range tests {
+		cfg := &Config{JwtOldSecrets: tt.listed}
+		err := cfg.loadOldSecrets(tt.environ)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: loadOldSecrets = %v, want error %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !maps.Equal(cfg.JwtOldSecrets, tt.want) {
+			t.Errorf("%s: old secrets = %v, want %v", tt.name, cfg.JwtOldSecrets, tt.want)
+		}
+	}
+}
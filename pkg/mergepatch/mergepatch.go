@@ -0,0 +1,61 @@
+// Package mergepatch applies JSON Merge Patches as defined by RFC 7396.
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ContentType is the media type of merge patch documents.
+const ContentType = "application/merge-patch+json"
+
+// Apply returns target with patch applied. Members of a patch object replace
+// those of the target, recursively for objects, and null members remove
+// them; any other patch document replaces the target whole.
+func Apply(target, patch []byte) ([]byte, error) {
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	var t any
+	if len(bytes.TrimSpace(target)) > 0 {
+		if t, err = decode(target); err != nil {
+			return nil, fmt.Errorf("invalid merge patch target: %w", err)
+		}
+	}
+	return json.Marshal(merge(t, p))
+}
+
+func merge(target, patch any) any {
+	members, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	result, ok := target.(map[string]any)
+	if !ok {
+		result = make(map[string]any, len(members))
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(result, name)
+			continue
+		}
+		result[name] = merge(result[name], value)
+	}
+	return result
+}
+
+// decode reads a single JSON value, keeping numbers exact.
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return v, nil
+}
@@ -0,0 +1,63 @@
+package mergepatch
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	// The examples of RFC 7396, Appendix A, and a few of our own.
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{"replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null removes member", `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"null keeps other members", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"value replaces array", `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"array replaces value", `{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{"nested objects merge", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"arrays are replaced whole", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"array target", `["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{"array patch", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"null patch", `{"a":"foo"}`, `null`, `null`},
+		{"string patch", `{"a":"foo"}`, `"bar"`, `"bar"`},
+		{"null in target stays", `{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{"object patch on array", `[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{"nested null on missing member", `{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{"empty target", ``, `{"a":1}`, `{"a":1}`},
+		{"empty patch", `{"a":1}`, `{}`, `{"a":1}`},
+		{"numbers stay exact", `{"n":1}`, `{"m":12345678901234567890.5}`, `{"m":12345678901234567890.5,"n":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.target), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Apply(%s, %s) = %s, want %s", tt.target, tt.patch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+	}{
+		{"invalid patch", `{}`, `{"a":`},
+		{"empty patch", `{}`, ``},
+		{"data after patch", `{}`, `{} {}`},
+		{"invalid target", `{"a"`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Apply([]byte(tt.target), []byte(tt.patch)); err == nil {
+				t.Errorf("Apply(%s, %s) = %s, want an error", tt.target, tt.patch, got)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 	"tasklist/internal/models"
@@ -9,7 +10,14 @@ import (
 	"tasklist/pkg/auth"
 )
 
-func JWTAuth() gin.HandlerFunc {
+// Sessions reports whether a login session is still active.
+type Sessions interface {
+	SessionActive(ctx context.Context, sessionId string) (bool, error)
+}
+
+// JWTAuth accepts requests with an access token verified by keys, of an
+// active session, and stores its user and session IDs in the context.
+func JWTAuth(keys *auth.Keyset, sessions Sessions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -21,12 +29,22 @@ func JWTAuth() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid auth header"})
 			return
 		}
-		claims, err := auth.ParseToken(parts[1])
-		if err != nil {
+		claims, err := keys.ParseToken(parts[1])
+		if err != nil || claims.SessionID == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
+		active, err := sessions.SessionActive(c, claims.SessionID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "session check failed"})
+			return
+		}
+		if !active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+			return
+		}
 		c.Set(models.UserCtxKey, claims.UserID)
+		c.Set(models.SessionCtxKey, claims.SessionID)
 		c.Next()
 	}
 }
@@ -0,0 +1,298 @@
+// Package recurrence parses task recurrence rules: the shorthands daily,
+// weekly, monthly and yearly, and a subset of RFC 5545 RRULEs.
+package recurrence
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+const (
+	maxInterval = 1000
+	untilFormat = "20060102T150405Z"
+	dateFormat  = "20060102"
+	// maxSkips bounds the periods searched for a valid date, such as a 31st
+	// or a 29 February.
+	maxSkips = 100
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+var shorthands = map[string]Frequency{
+	"daily":   Daily,
+	"weekly":  Weekly,
+	"monthly": Monthly,
+	"yearly":  Yearly,
+}
+
+// Rule is a parsed recurrence rule. Occurrences keep the time of day of the
+// occurrence they follow. Count, when non-zero, is the number of occurrences
+// left in the series, including the current one.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+}
+
+// Parse reads a shorthand or an RRULE with FREQ, INTERVAL, BYDAY (DAILY and
+// WEEKLY only, without ordinals), BYMONTHDAY (MONTHLY only), COUNT and UNTIL.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimSpace(s)
+	if freq, ok := shorthands[strings.ToLower(s)]; ok {
+		return Rule{Freq: freq, Interval: 1}, nil
+	}
+
+	rule := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.ToUpper(s), "RRULE:")
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		if seen[key] {
+			return Rule{}, fmt.Errorf("recurrence rule repeats %s", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(value)
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				err = fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = parseInt(key, value, 1, maxInterval)
+		case "COUNT":
+			rule.Count, err = parseInt(key, value, 1, maxInterval)
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return Rule{}, fmt.Errorf("unsupported BYDAY value %q", day)
+				}
+				if !slices.Contains(rule.ByDay, weekday) {
+					rule.ByDay = append(rule.ByDay, weekday)
+				}
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := parseInt(key, day, -31, 31)
+				if err != nil || n == 0 {
+					return Rule{}, fmt.Errorf("BYMONTHDAY values must be between 1 and 31 or -31 and -1")
+				}
+				if !slices.Contains(rule.ByMonthDay, n) {
+					rule.ByMonthDay = append(rule.ByMonthDay, n)
+				}
+			}
+		case "UNTIL":
+			until, perr := time.Parse(untilFormat, value)
+			if perr != nil {
+				until, perr = time.Parse(dateFormat, value)
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			if perr != nil {
+				err = fmt.Errorf("UNTIL must be a date or UTC date-time such as 20260131T170000Z")
+			}
+			rule.Until = &until
+		default:
+			err = fmt.Errorf("unsupported recurrence rule part %s", key)
+		}
+		if err != nil {
+			return Rule{}, err
+		}
+	}
+
+	switch {
+	case rule.Freq == "":
+		return Rule{}, fmt.Errorf("recurrence rule needs a FREQ")
+	case rule.Count > 0 && rule.Until != nil:
+		return Rule{}, fmt.Errorf("recurrence rule cannot have both COUNT and UNTIL")
+	case len(rule.ByDay) > 0 && rule.Freq != Daily && rule.Freq != Weekly:
+		return Rule{}, fmt.Errorf("BYDAY is only supported with FREQ=DAILY or FREQ=WEEKLY")
+	case len(rule.ByMonthDay) > 0 && rule.Freq != Monthly:
+		return Rule{}, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	slices.SortFunc(rule.ByDay, func(a, b time.Weekday) int { return mondayFirst(a) - mondayFirst(b) })
+	slices.Sort(rule.ByMonthDay)
+	return rule, nil
+}
+
+func parseInt(key, value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be between %d and %d", key, min, max)
+	}
+	return n, nil
+}
+
+// String formats r as an RRULE without the "RRULE:" prefix.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, weekday := range r.ByDay {
+			days[i] = strings.ToUpper(weekday.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilFormat))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Advance returns the first occurrence after both from and notBefore, so that
+// a late completion does not schedule occurrences that are already past. The
+// returned rule continues the series, with Count reduced by the occurrences
+// passed. It reports false once the series has ended.
+func (r Rule) Advance(from, notBefore time.Time) (time.Time, Rule, bool) {
+	next := from
+	for {
+		if r.Count == 1 {
+			return time.Time{}, r, false
+		}
+		var ok bool
+		if next, ok = r.Next(next); !ok {
+			return time.Time{}, r, false
+		}
+		if r.Count > 0 {
+			r.Count--
+		}
+		if next.After(notBefore) {
+			return next, r, true
+		}
+	}
+}
+
+// Next returns the occurrence following t, ignoring Count. It reports false
+// when there is none before Until.
+func (r Rule) Next(t time.Time) (time.Time, bool) {
+	var next time.Time
+	var ok bool
+	switch r.Freq {
+	case Daily:
+		next, ok = r.nextDaily(t)
+	case Weekly:
+		next, ok = r.nextWeekly(t)
+	case Monthly:
+		next, ok = r.nextMonthly(t)
+	case Yearly:
+		next, ok = r.nextYearly(t)
+	}
+	if !ok || (r.Until != nil && next.After(*r.Until)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+func (r Rule) nextDaily(t time.Time) (time.Time, bool) {
+	// Weekdays repeat after seven steps, so a day not found by then never
+	// comes.
+	for i := 1; i <= 7; i++ {
+		t = t.AddDate(0, 0, r.Interval)
+		if len(r.ByDay) == 0 || slices.Contains(r.ByDay, t.Weekday()) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (r Rule) nextWeekly(t time.Time) (time.Time, bool) {
+	if len(r.ByDay) == 0 {
+		return t.AddDate(0, 0, 7*r.Interval), true
+	}
+	// Later days of the same week, which starts on Monday.
+	for d := t.AddDate(0, 0, 1); d.Weekday() != time.Monday; d = d.AddDate(0, 0, 1) {
+		if slices.Contains(r.ByDay, d.Weekday()) {
+			return d, true
+		}
+	}
+	weekStart := t.AddDate(0, 0, -mondayFirst(t.Weekday())+7*r.Interval)
+	return weekStart.AddDate(0, 0, mondayFirst(r.ByDay[0])), true
+}
+
+func (r Rule) nextMonthly(t time.Time) (time.Time, bool) {
+	days := r.ByMonthDay
+	if len(days) == 0 {
+		days = []int{t.Day()}
+	}
+	for i := 0; i <= maxSkips; i++ {
+		month := time.Date(t.Year(), t.Month()+time.Month(i*r.Interval), 1,
+			t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		last := month.AddDate(0, 1, -1).Day()
+
+		var candidates []int
+		for _, day := range days {
+			if day < 0 {
+				day += last + 1
+			}
+			if day >= 1 && day <= last {
+				candidates = append(candidates, day)
+			}
+		}
+		slices.Sort(candidates)
+		for _, day := range candidates {
+			if d := month.AddDate(0, 0, day-1); d.After(t) {
+				return d, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func (r Rule) nextYearly(t time.Time) (time.Time, bool) {
+	for i := 1; i <= maxSkips; i++ {
+		d := time.Date(t.Year()+i*r.Interval, t.Month(), t.Day(),
+			t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		// Skip years in which the date does not exist, such as 29 February.
+		if d.Day() == t.Day() {
+			return d, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// mondayFirst numbers weekdays from Monday (0) to Sunday (6).
+func mondayFirst(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
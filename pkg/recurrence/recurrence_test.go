@@ -0,0 +1,168 @@
+package recurrence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	until := date(2026, time.January, 31, 0).Add(24*time.Hour - time.Second)
+	tests := []struct {
+		in   string
+		want Rule
+	}{
+		{"daily", Rule{Freq: Daily, Interval: 1}},
+		{" Weekly ", Rule{Freq: Weekly, Interval: 1}},
+		{"FREQ=YEARLY", Rule{Freq: Yearly, Interval: 1}},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=FR,MO,FR",
+			Rule{Freq: Weekly, Interval: 2, ByDay: []time.Weekday{time.Monday, time.Friday}}},
+		{"freq=daily;byday=su,sa", Rule{Freq: Daily, Interval: 1, ByDay: []time.Weekday{time.Saturday, time.Sunday}}},
+		{"FREQ=MONTHLY;BYMONTHDAY=15,-1", Rule{Freq: Monthly, Interval: 1, ByMonthDay: []int{-1, 15}}},
+		{"FREQ=DAILY;COUNT=3", Rule{Freq: Daily, Interval: 1, Count: 3}},
+		{"FREQ=DAILY;UNTIL=20260131", Rule{Freq: Daily, Interval: 1, Until: &until}},
+		{"FREQ=DAILY;UNTIL=20260131T235959Z", Rule{Freq: Daily, Interval: 1, Until: &until}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"hourly",
+		"FREQ=HOURLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL=1001",
+		"FREQ=DAILY;COUNT=x",
+		"FREQ=DAILY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ=DAILY;",
+	}
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			if rule, err := Parse(in); err == nil {
+				t.Errorf("Parse(%q) = %+v, want an error", in, rule)
+			}
+		})
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	for _, in := range []string{
+		"FREQ=DAILY",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE,FR",
+		"FREQ=MONTHLY;BYMONTHDAY=-1,1",
+		"FREQ=YEARLY;COUNT=5",
+		"FREQ=DAILY;UNTIL=20260131T170000Z",
+	} {
+		rule, err := Parse(in)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", in, err)
+		}
+		if got := rule.String(); got != in {
+			t.Errorf("Parse(%q).String() = %q", in, got)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2026-01-01 is a Thursday.
+	tests := []struct {
+		name string
+		rule string
+		from time.Time
+		want time.Time
+		ok   bool
+	}{
+		{"daily", "daily", date(2026, time.January, 31, 9), date(2026, time.February, 1, 9), true},
+		{"daily interval", "FREQ=DAILY;INTERVAL=3", date(2026, time.December, 30, 9), date(2027, time.January, 2, 9), true},
+		{"daily byday", "FREQ=DAILY;BYDAY=MO,FR", date(2026, time.January, 9, 9), date(2026, time.January, 12, 9), true},
+		{"daily interval byday", "FREQ=DAILY;INTERVAL=2;BYDAY=SA", date(2026, time.January, 9, 9), date(2026, time.January, 17, 9), true},
+		{"daily byday never reached", "FREQ=DAILY;INTERVAL=7;BYDAY=MO", date(2026, time.January, 6, 9), time.Time{}, false},
+		{"weekly", "weekly", date(2026, time.January, 1, 9), date(2026, time.January, 8, 9), true},
+		{"weekly byday same week", "FREQ=WEEKLY;BYDAY=MO,WE,FR", date(2026, time.January, 7, 9), date(2026, time.January, 9, 9), true},
+		{"weekly byday next week", "FREQ=WEEKLY;BYDAY=MO,WE,FR", date(2026, time.January, 9, 9), date(2026, time.January, 12, 9), true},
+		{"weekly byday sunday ends week", "FREQ=WEEKLY;BYDAY=MO,SU", date(2026, time.January, 11, 9), date(2026, time.January, 12, 9), true},
+		{"weekly interval byday", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE,FR", date(2026, time.January, 9, 9), date(2026, time.January, 19, 9), true},
+		{"monthly", "monthly", date(2026, time.January, 15, 9), date(2026, time.February, 15, 9), true},
+		{"monthly skips short months", "monthly", date(2026, time.January, 31, 9), date(2026, time.March, 31, 9), true},
+		{"monthly 30th skips february", "monthly", date(2026, time.January, 30, 9), date(2026, time.March, 30, 9), true},
+		{"monthly last day", "FREQ=MONTHLY;BYMONTHDAY=-1", date(2026, time.January, 31, 9), date(2026, time.February, 28, 9), true},
+		{"monthly last day leap year", "FREQ=MONTHLY;BYMONTHDAY=-1", date(2024, time.January, 31, 9), date(2024, time.February, 29, 9), true},
+		{"monthly later day same month", "FREQ=MONTHLY;BYMONTHDAY=1,15", date(2026, time.January, 10, 9), date(2026, time.January, 15, 9), true},
+		{"monthly first day next month", "FREQ=MONTHLY;BYMONTHDAY=1,15", date(2026, time.January, 15, 9), date(2026, time.February, 1, 9), true},
+		{"monthly interval across year", "FREQ=MONTHLY;INTERVAL=2", date(2026, time.November, 5, 9), date(2027, time.January, 5, 9), true},
+		{"yearly", "yearly", date(2026, time.March, 1, 9), date(2027, time.March, 1, 9), true},
+		{"yearly leap day", "yearly", date(2024, time.February, 29, 9), date(2028, time.February, 29, 9), true},
+		{"until reached", "FREQ=DAILY;UNTIL=20260110", date(2026, time.January, 10, 9), time.Time{}, false},
+		{"until not reached", "FREQ=DAILY;UNTIL=20260110", date(2026, time.January, 9, 9), date(2026, time.January, 10, 9), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.rule, err)
+			}
+			got, ok := rule.Next(tt.from)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, %v; want %s, %v", tt.from, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	from := date(2026, time.January, 1, 9)
+	tests := []struct {
+		name      string
+		rule      string
+		notBefore time.Time
+		want      time.Time
+		wantCount int
+		ok        bool
+	}{
+		{"on time", "FREQ=DAILY;COUNT=3", from, date(2026, time.January, 2, 9), 2, true},
+		{"late skips past occurrences", "FREQ=DAILY;COUNT=10", date(2026, time.January, 5, 12), date(2026, time.January, 6, 9), 5, true},
+		{"late past the end", "FREQ=DAILY;COUNT=3", date(2026, time.January, 5, 12), time.Time{}, 0, false},
+		{"last occurrence", "FREQ=DAILY;COUNT=1", from, time.Time{}, 0, false},
+		{"without count", "daily", date(2026, time.January, 3, 9), date(2026, time.January, 4, 9), 0, true},
+		{"until", "FREQ=DAILY;UNTIL=20260103", date(2026, time.January, 3, 12), time.Time{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.rule, err)
+			}
+			got, rest, ok := rule.Advance(from, tt.notBefore)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Fatalf("Advance = %s, %v; want %s, %v", got, ok, tt.want, tt.ok)
+			}
+			if ok && rest.Count != tt.wantCount {
+				t.Errorf("Advance left Count %d, want %d", rest.Count, tt.wantCount)
+			}
+		})
+	}
+}